@@ -0,0 +1,202 @@
+package article
+
+// prepare article html before adding news: images outside wechat are uploaded
+// by media/uploadimg and src rewritten, tags not allowed are stripped, and
+// lengths of fields are checked against limits of wechat.
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MenInBack/weshin/base"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// limits of article
+const (
+	MaxTitleLen     = 64 // characters
+	MaxAuthorLen    = 16
+	MaxDigestLen    = 120
+	MaxContentLen   = 20000 // content should be less than
+	MaxContentBytes = 1 << 20
+)
+
+const defaultFetchTimeout = 10 // seconds, as wx.HttpClient
+
+// hosts of images on wechat, not uploaded again
+var wechatImageHosts = []string{"mmbiz.qpic.cn", "mmbiz.qlogo.cn"}
+
+var (
+	imgPattern = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+	srcPattern = regexp.MustCompile(`(?is)(\s(?:data-)?src\s*=\s*)("[^"]*"|'[^']*'|[^\s"'>]+)`)
+
+	// tags stripped with content
+	blockPattern = regexp.MustCompile(`(?is)<(script|style|iframe|object|applet|frameset|noscript|textarea|select)\b.*?</(script|style|iframe|object|applet|frameset|noscript|textarea|select)\s*>`)
+	// tags stripped, content kept
+	tagPattern = regexp.MustCompile(`(?is)</?(script|style|iframe|object|applet|frameset|frame|noscript|textarea|select|form|input|button|embed|link|meta|base)\b[^>]*>`)
+	// event handlers and scripts in attributes
+	eventPattern      = regexp.MustCompile(`(?is)\s+on[a-z]+\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+	javascriptPattern = regexp.MustCompile(`(?is)(\s(?:href|src)\s*=\s*["']?)\s*javascript:[^"'\s>]*`)
+)
+
+// ImageUploader of article images, implemented by *base.MP.
+type ImageUploader interface {
+	UploadArticleImage(filename string, r io.Reader, timeout int) (url string, err error)
+}
+
+// Preparer prepares articles before adding news.
+type Preparer struct {
+	Uploader ImageUploader
+	// Fetch returns content of image at src, http GET in Timeout if nil.
+	Fetch   func(src string) (io.ReadCloser, error)
+	Timeout int // seconds of fetching and uploading each image
+}
+
+// Prepare content of article and checks limits of article.
+func (p *Preparer) Prepare(a *base.NewsArticle) error {
+	content, err := p.PrepareHTML(a.Content)
+	if err != nil {
+		return err
+	}
+	a.Content = content
+	return Check(a)
+}
+
+// PrepareHTML strips tags not allowed and uploads images outside wechat,
+// an image referred more than once is uploaded once.
+func (p *Preparer) PrepareHTML(content string) (string, error) {
+	content = Sanitize(content)
+
+	uploaded := make(map[string]string)
+	var err error
+	content = imgPattern.ReplaceAllStringFunc(content, func(img string) string {
+		if err != nil {
+			return img
+		}
+		return srcPattern.ReplaceAllStringFunc(img, func(attr string) string {
+			if err != nil {
+				return attr
+			}
+			m := srcPattern.FindStringSubmatch(attr)
+			src := strings.Trim(m[2], `"'`)
+			if !external(src) {
+				return attr
+			}
+
+			u, ok := uploaded[src]
+			if !ok {
+				u, err = p.upload(src)
+				if err != nil {
+					return attr
+				}
+				uploaded[src] = u
+			}
+			return m[1] + `"` + u + `"`
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+func (p *Preparer) upload(src string) (string, error) {
+	fetch := p.Fetch
+	if fetch == nil {
+		fetch = p.httpFetch
+	}
+	r, err := fetch(src)
+	if err != nil {
+		return "", fmt.Errorf("fetch image %s: %s", src, err)
+	}
+	defer r.Close()
+
+	return p.Uploader.UploadArticleImage(filename(src), r, p.Timeout)
+}
+
+func (p *Preparer) httpFetch(src string) (io.ReadCloser, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultFetchTimeout
+	}
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Second}
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, wx.HttpError{State: resp.StatusCode}
+	}
+	return resp.Body, nil
+}
+
+// external tells whether image at src should be uploaded to wechat.
+func external(src string) bool {
+	u, err := url.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false // relative or data urls are left to wechat
+	}
+	for _, host := range wechatImageHosts {
+		if u.Host == host || strings.HasSuffix(u.Host, "."+host) {
+			return false
+		}
+	}
+	return true
+}
+
+func filename(src string) string {
+	u, _ := url.Parse(src)
+	name := path.Base(u.Path)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png":
+		return name
+	}
+	// wechat recognizes format by extension, while content is checked anyway
+	if q := u.Query().Get("wx_fmt"); q == "png" {
+		return "image.png"
+	}
+	return "image.jpg"
+}
+
+// Sanitize strips scripts, styles, frames and form controls,
+// with event handlers and javascript urls in attributes.
+func Sanitize(content string) string {
+	content = blockPattern.ReplaceAllString(content, "")
+	content = tagPattern.ReplaceAllString(content, "")
+	content = eventPattern.ReplaceAllString(content, "")
+	content = javascriptPattern.ReplaceAllString(content, "$1")
+	return content
+}
+
+// Check lengths of article fields against limits of wechat.
+func Check(a *base.NewsArticle) error {
+	for _, f := range []struct {
+		name  string
+		value string
+		limit int
+	}{
+		{"title", a.Title, MaxTitleLen},
+		{"author", a.Author, MaxAuthorLen},
+		{"digest", a.Digest, MaxDigestLen},
+		{"content", a.Content, MaxContentLen - 1},
+	} {
+		if n := utf8.RuneCountInString(f.value); n > f.limit {
+			return wx.ParameterError{InvalidParameter: fmt.Sprintf("%s: %d characters, expect no more than %d", f.name, n, f.limit)}
+		}
+	}
+	if len(a.Title) == 0 {
+		return wx.ParameterError{InvalidParameter: "title"}
+	}
+	if len(a.Content) == 0 || len(a.Content) >= MaxContentBytes {
+		return wx.ParameterError{InvalidParameter: fmt.Sprintf("content: %d bytes, expect less than %d", len(a.Content), MaxContentBytes)}
+	}
+	return nil
+}
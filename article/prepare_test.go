@@ -0,0 +1,114 @@
+package article
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MenInBack/weshin/base"
+)
+
+type fakeUploader struct {
+	uploaded []string
+}
+
+func (u *fakeUploader) UploadArticleImage(filename string, r io.Reader, timeout int) (string, error) {
+	data, _ := ioutil.ReadAll(r)
+	u.uploaded = append(u.uploaded, filename)
+	return "http://mmbiz.qpic.cn/mmbiz/" + string(data), nil
+}
+
+func TestPrepareHTML(t *testing.T) {
+	uploader := new(fakeUploader)
+	p := &Preparer{
+		Uploader: uploader,
+		Fetch: func(src string) (io.ReadCloser, error) {
+			if strings.Contains(src, "missing") {
+				return nil, errors.New("not found")
+			}
+			return ioutil.NopCloser(strings.NewReader(src[strings.LastIndex(src, "/")+1:])), nil
+		},
+	}
+
+	content := `<p onclick="alert(1)">hello</p><script>alert(2)</script>` +
+		`<img src="https://example.com/a.png" alt="a">` +
+		`<img data-src='https://example.com/a.png' src=https://example.com/b>` +
+		`<img src="https://mmbiz.qpic.cn/mmbiz_jpg/c.jpg">` +
+		`<a href="javascript:alert(3)">link</a><form action="/x"><input name="q">text</form>`
+	prepared, err := p.PrepareHTML(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `<p>hello</p>` +
+		`<img src="http://mmbiz.qpic.cn/mmbiz/a.png" alt="a">` +
+		`<img data-src="http://mmbiz.qpic.cn/mmbiz/a.png" src="http://mmbiz.qpic.cn/mmbiz/b">` +
+		`<img src="https://mmbiz.qpic.cn/mmbiz_jpg/c.jpg">` +
+		`<a href="">link</a>text`
+	if prepared != expect {
+		t.Errorf("prepared:\n%s\nexpect:\n%s", prepared, expect)
+	}
+	if strings.Join(uploader.uploaded, ",") != "a.png,image.jpg" {
+		t.Error("uploaded: ", uploader.uploaded)
+	}
+
+	_, err = p.PrepareHTML(`<img src="https://example.com/missing.jpg">`)
+	if err == nil || !strings.Contains(err.Error(), "missing.jpg") {
+		t.Error("expect fetch error, got ", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	a := &base.NewsArticle{Title: "标题", Content: "<p>内容</p>", Digest: strings.Repeat("摘", MaxDigestLen)}
+	if err := Check(a); err != nil {
+		t.Error(err)
+	}
+	a.Digest += "要"
+	if err := Check(a); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Error("expect digest too long, got ", err)
+	}
+	a.Digest = ""
+	a.Content = strings.Repeat("内", MaxContentLen-1)
+	if err := Check(a); err != nil {
+		t.Error(err)
+	}
+	a.Content += "容"
+	if err := Check(a); err == nil || !strings.Contains(err.Error(), "content") {
+		t.Error("expect content too long, got ", err)
+	}
+}
+
+func TestHTTPFetch(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/stalled.jpg" {
+			<-release
+		}
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p := &Preparer{Timeout: 1}
+	r, err := p.httpFetch(server.URL + "/a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(r)
+	r.Close()
+	if string(data) != "image" {
+		t.Errorf("fetched %q", data)
+	}
+
+	start := time.Now()
+	if _, err = p.httpFetch(server.URL + "/stalled.jpg"); err == nil {
+		t.Error("expect timeout of stalled image")
+	}
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("fetch returned after %s", d)
+	}
+}
@@ -43,10 +43,9 @@ func (mp *MP) GetUserInfo(openID, lang string, timeout int) (userinfo *wx.UserIn
 	if len(openID) <= 0 {
 		return nil, wx.ParameterError{InvalidParameter: "openID"}
 	}
-	if len(lang) <= 0 {
-		lang = wx.LangCN
-	} else if lang != wx.LangCN && lang != wx.LangEN && lang != wx.LangTW {
-		return nil, wx.ParameterError{InvalidParameter: "lang"}
+	lang, err = checkLang(lang)
+	if err != nil {
+		return nil, err
 	}
 
 	req := wx.HttpClient{
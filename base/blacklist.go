@@ -0,0 +1,72 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 用户管理/黑名单管理
+ */
+
+import (
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	blacklistGetPath   = "https://api.weixin.qq.com/cgi-bin/tags/members/getblacklist"
+	blacklistBatchPath = "https://api.weixin.qq.com/cgi-bin/tags/members/batchblacklist"
+	unblacklistPath    = "https://api.weixin.qq.com/cgi-bin/tags/members/batchunblacklist"
+)
+
+const maxBlacklistUsers = 20
+
+// GetBlacklist returns a page of at most 10000 blocked users after beginOpenID,
+// from the beginning if beginOpenID is empty.
+// https://api.weixin.qq.com/cgi-bin/tags/members/getblacklist?access_token=ACCESS_TOKEN
+func (mp *MP) GetBlacklist(beginOpenID string, timeout int) (list *OpenIDList, err error) {
+	body := struct {
+		BeginOpenID string `json:"begin_openid"`
+	}{beginOpenID}
+
+	list = new(OpenIDList)
+	err = mp.postJSON(blacklistGetPath, body, list, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// BlacklistIterator iterates all blocked users.
+func (mp *MP) BlacklistIterator(timeout int) *OpenIDIterator {
+	return NewOpenIDIterator("", func(nextOpenID string) (*OpenIDList, error) {
+		return mp.GetBlacklist(nextOpenID, timeout)
+	})
+}
+
+// BlockUsers adds users into blacklist in chunks of 20 openids,
+// users in chunks before error returned are blocked.
+// https://api.weixin.qq.com/cgi-bin/tags/members/batchblacklist?access_token=ACCESS_TOKEN
+func (mp *MP) BlockUsers(openIDs []string, timeout int) error {
+	return mp.batchBlacklist(blacklistBatchPath, openIDs, timeout)
+}
+
+// UnblockUsers removes users from blacklist in chunks of 20 openids,
+// users in chunks before error returned are unblocked.
+// https://api.weixin.qq.com/cgi-bin/tags/members/batchunblacklist?access_token=ACCESS_TOKEN
+func (mp *MP) UnblockUsers(openIDs []string, timeout int) error {
+	return mp.batchBlacklist(unblacklistPath, openIDs, timeout)
+}
+
+func (mp *MP) batchBlacklist(path string, openIDs []string, timeout int) error {
+	if len(openIDs) <= 0 {
+		return wx.ParameterError{InvalidParameter: "openIDs"}
+	}
+
+	for _, c := range chunk(openIDs, maxBlacklistUsers) {
+		body := struct {
+			OpenIDList []string `json:"openid_list"`
+		}{c}
+
+		err := mp.postJSON(path, body, nil, timeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package base
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+func TestBatchBlacklist(t *testing.T) {
+	for _, c := range []struct {
+		users  int
+		chunks []int
+		ok     bool
+	}{
+		{0, nil, false},
+		{1, []int{1}, true},
+		{20, []int{20}, true},
+		{21, []int{20, 1}, true},
+		{45, []int{20, 20, 5}, true},
+	} {
+		for _, path := range []string{blacklistBatchPath, unblacklistPath} {
+			api, stop := serveFakeAPI(nil)
+			mp := newTestMP()
+			var err error
+			if path == blacklistBatchPath {
+				err = mp.BlockUsers(openIDs(c.users), 0)
+			} else {
+				err = mp.UnblockUsers(openIDs(c.users), 0)
+			}
+			stop()
+
+			if (err == nil) != c.ok {
+				t.Errorf("%d users: error %v", c.users, err)
+			}
+			requests := api.served(path)
+			if len(requests) != len(c.chunks) {
+				t.Errorf("%d users: %d requests to %s", c.users, len(requests), path)
+				continue
+			}
+			for i, r := range requests {
+				var body struct {
+					OpenIDList []string `json:"openid_list"`
+				}
+				json.Unmarshal(r.Body, &body)
+				if len(body.OpenIDList) != c.chunks[i] {
+					t.Errorf("%d users: chunk %d of %d users", c.users, i, len(body.OpenIDList))
+				}
+			}
+		}
+	}
+
+	// chunks after error are not sent
+	api, stop := serveFakeAPI(func(r *apiRequest) string {
+		if strings.Contains(string(r.Body), `"openid20"`) {
+			return `{"errcode":49003,"errmsg":"not match"}`
+		}
+		return `{"errcode":0,"errmsg":"ok"}`
+	})
+	defer stop()
+	if err := newTestMP().BlockUsers(openIDs(60), 0); err == nil {
+		t.Error("expect error of the second chunk")
+	}
+	if n := len(api.served(blacklistBatchPath)); n != 2 {
+		t.Errorf("%d requests sent", n)
+	}
+}
+
+func TestUpdateRemark(t *testing.T) {
+	for _, c := range []struct {
+		openID string
+		remark string
+		ok     bool
+	}{
+		{"openid", "", true},
+		{"openid", strings.Repeat("备", 29), true},
+		{"openid", strings.Repeat("备", 30), false},
+		{"openid", strings.Repeat("r", 30), false},
+		{"", "remark", false},
+	} {
+		api, stop := serveFakeAPI(nil)
+		user := &wx.UserInfo{OpenID: c.openID, Remark: "old"}
+		err := newTestMP().UpdateRemark(user, c.remark, 0)
+		stop()
+
+		if (err == nil) != c.ok {
+			t.Errorf("%q of %d characters: error %v", c.openID, len([]rune(c.remark)), err)
+		}
+		requests := api.served(updateRemarkPath)
+		if !c.ok {
+			if len(requests) != 0 || user.Remark != "old" {
+				t.Errorf("%q: invalid remark sent", c.openID)
+			}
+			continue
+		}
+		if len(requests) != 1 || user.Remark != c.remark {
+			t.Errorf("%q: %d requests, remark %q", c.openID, len(requests), user.Remark)
+		}
+	}
+}
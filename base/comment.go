@@ -0,0 +1,194 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 图文消息留言管理
+ */
+
+import (
+	"fmt"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	commentOpenPath        = "https://api.weixin.qq.com/cgi-bin/comment/open"
+	commentClosePath       = "https://api.weixin.qq.com/cgi-bin/comment/close"
+	commentListPath        = "https://api.weixin.qq.com/cgi-bin/comment/list"
+	commentMarkElectPath   = "https://api.weixin.qq.com/cgi-bin/comment/markelect"
+	commentUnmarkElectPath = "https://api.weixin.qq.com/cgi-bin/comment/unmarkelect"
+	commentDeletePath      = "https://api.weixin.qq.com/cgi-bin/comment/delete"
+	commentReplyAddPath    = "https://api.weixin.qq.com/cgi-bin/comment/reply/add"
+	commentReplyDeletePath = "https://api.weixin.qq.com/cgi-bin/comment/reply/delete"
+	MaxCommentCount        = 50
+)
+
+// type of comments listed
+const (
+	CommentAll     = 0
+	CommentNormal  = 1
+	CommentElected = 2
+)
+
+// ArticleRef refers to article by msg_data_id returned by mass send or publish,
+// and index of article in it, starting from 0.
+type ArticleRef struct {
+	MsgDataID int64 `json:"msg_data_id"`
+	Index     int   `json:"index"`
+}
+
+// Comment on article.
+type Comment struct {
+	UserCommentID int64  `json:"user_comment_id"`
+	OpenID        string `json:"openid"`
+	CreateTime    int64  `json:"create_time"`
+	Content       string `json:"content"`
+	CommentType   int32  `json:"comment_type"` // 1 for elected
+	Reply         *struct {
+		Content    string `json:"content"`
+		CreateTime int64  `json:"create_time"`
+	} `json:"reply,omitempty"`
+}
+
+// CommentList is a page of comments.
+type CommentList struct {
+	Total   int       `json:"total"`
+	Comment []Comment `json:"comment"`
+}
+
+type commentRef struct {
+	ArticleRef
+	UserCommentID int64 `json:"user_comment_id"`
+}
+
+// OpenComment of article.
+// https://api.weixin.qq.com/cgi-bin/comment/open?access_token=ACCESS_TOKEN
+func (mp *MP) OpenComment(article ArticleRef, timeout int) error {
+	return mp.postJSON(commentOpenPath, article, nil, timeout)
+}
+
+// CloseComment of article.
+// https://api.weixin.qq.com/cgi-bin/comment/close?access_token=ACCESS_TOKEN
+func (mp *MP) CloseComment(article ArticleRef, timeout int) error {
+	return mp.postJSON(commentClosePath, article, nil, timeout)
+}
+
+// ListComments of type from begin, no more than 50 at a time.
+// https://api.weixin.qq.com/cgi-bin/comment/list?access_token=ACCESS_TOKEN
+func (mp *MP) ListComments(article ArticleRef, commentType, begin, count int, timeout int) (list *CommentList, err error) {
+	if commentType < CommentAll || commentType > CommentElected {
+		return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("type: %d", commentType)}
+	}
+	if begin < 0 {
+		return nil, wx.ParameterError{InvalidParameter: "begin"}
+	}
+	if count <= 0 || count > MaxCommentCount {
+		return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("count: %d, expect 1 to %d", count, MaxCommentCount)}
+	}
+
+	body := struct {
+		ArticleRef
+		Begin int `json:"begin"`
+		Count int `json:"count"`
+		Type  int `json:"type"`
+	}{article, begin, count, commentType}
+
+	list = new(CommentList)
+	err = mp.postJSON(commentListPath, body, list, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// MarkElectComment shows comment to all readers.
+// https://api.weixin.qq.com/cgi-bin/comment/markelect?access_token=ACCESS_TOKEN
+func (mp *MP) MarkElectComment(article ArticleRef, commentID int64, timeout int) error {
+	return mp.postJSON(commentMarkElectPath, commentRef{article, commentID}, nil, timeout)
+}
+
+// UnmarkElectComment hides comment from readers other than commenter.
+// https://api.weixin.qq.com/cgi-bin/comment/unmarkelect?access_token=ACCESS_TOKEN
+func (mp *MP) UnmarkElectComment(article ArticleRef, commentID int64, timeout int) error {
+	return mp.postJSON(commentUnmarkElectPath, commentRef{article, commentID}, nil, timeout)
+}
+
+// DeleteComment of article.
+// https://api.weixin.qq.com/cgi-bin/comment/delete?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteComment(article ArticleRef, commentID int64, timeout int) error {
+	return mp.postJSON(commentDeletePath, commentRef{article, commentID}, nil, timeout)
+}
+
+// ReplyComment with content, replacing reply if any.
+// https://api.weixin.qq.com/cgi-bin/comment/reply/add?access_token=ACCESS_TOKEN
+func (mp *MP) ReplyComment(article ArticleRef, commentID int64, content string, timeout int) error {
+	if len(content) <= 0 {
+		return wx.ParameterError{InvalidParameter: "content"}
+	}
+
+	body := struct {
+		commentRef
+		Content string `json:"content"`
+	}{commentRef{article, commentID}, content}
+
+	return mp.postJSON(commentReplyAddPath, body, nil, timeout)
+}
+
+// DeleteCommentReply of comment.
+// https://api.weixin.qq.com/cgi-bin/comment/reply/delete?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteCommentReply(article ArticleRef, commentID int64, timeout int) error {
+	return mp.postJSON(commentReplyDeletePath, commentRef{article, commentID}, nil, timeout)
+}
+
+// CommentIterator iterates comments of article page by page.
+//
+//	it := mp.CommentIterator(article, CommentAll, 0)
+//	for it.Next() {
+//		comment := it.Comment()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type CommentIterator struct {
+	fetch   func(begin, count int) (*CommentList, error)
+	pager   offsetPager
+	page    []Comment
+	comment Comment
+}
+
+// CommentIterator iterates comments of type.
+func (mp *MP) CommentIterator(article ArticleRef, commentType int, timeout int) *CommentIterator {
+	return &CommentIterator{
+		fetch: func(begin, count int) (*CommentList, error) {
+			return mp.ListComments(article, commentType, begin, count, timeout)
+		},
+	}
+}
+
+// Next moves to the next comment, false if no more or error occurred.
+func (it *CommentIterator) Next() bool {
+	for len(it.page) == 0 {
+		ok := it.pager.next(func(begin int) (int, int, error) {
+			list, err := it.fetch(begin, MaxCommentCount)
+			if err != nil {
+				return 0, 0, err
+			}
+			it.page = list.Comment
+			return len(list.Comment), list.Total, nil
+		})
+		if !ok {
+			return false
+		}
+	}
+
+	it.comment, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Comment the iterator is at.
+func (it *CommentIterator) Comment() Comment {
+	return it.comment
+}
+
+// Err occurred while fetching pages.
+func (it *CommentIterator) Err() error {
+	return it.pager.err
+}
@@ -0,0 +1,66 @@
+package base
+
+import "testing"
+
+func TestCommentRequests(t *testing.T) {
+	api, stop := serveFakeAPI(nil)
+	defer stop()
+
+	mp := newTestMP()
+	article := ArticleRef{MsgDataID: 2247483782, Index: 1}
+	if err := mp.ReplyComment(article, 3, "reply", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mp.ListComments(article, CommentElected, 50, 20, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expect := range map[string]string{
+		commentReplyAddPath: `{"msg_data_id":2247483782,"index":1,"user_comment_id":3,"content":"reply"}`,
+		commentListPath:     `{"msg_data_id":2247483782,"index":1,"begin":50,"count":20,"type":2}`,
+	} {
+		requests := api.served(path)
+		if len(requests) != 1 || string(requests[0].Body) != expect {
+			t.Errorf("%s: %d requests, expect 1 of %s", path, len(requests), expect)
+		}
+	}
+
+	// invalid parameters are not sent
+	for _, err := range []error{
+		mp.ReplyComment(article, 3, "", 0),
+		func() error { _, err := mp.ListComments(article, 3, 0, 20, 0); return err }(),
+		func() error { _, err := mp.ListComments(article, CommentAll, 0, MaxCommentCount+1, 0); return err }(),
+	} {
+		if err == nil {
+			t.Error("expect parameter error")
+		}
+	}
+	if len(api.requests) != 2 {
+		t.Errorf("%d requests sent", len(api.requests))
+	}
+}
+
+func TestCommentIterator(t *testing.T) {
+	var begins []int
+	it := &CommentIterator{
+		fetch: func(begin, count int) (*CommentList, error) {
+			begins = append(begins, begin)
+			list := &CommentList{Total: 120}
+			for i := begin; i < 120 && i < begin+count; i++ {
+				list.Comment = append(list.Comment, Comment{UserCommentID: int64(i)})
+			}
+			return list, nil
+		},
+	}
+
+	var n int64
+	for it.Next() {
+		if it.Comment().UserCommentID != n {
+			t.Fatalf("comment %d: %d", n, it.Comment().UserCommentID)
+		}
+		n++
+	}
+	if it.Err() != nil || n != 120 || len(begins) != 3 {
+		t.Errorf("iterated %d comments in %v, error %v", n, begins, it.Err())
+	}
+}
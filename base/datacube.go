@@ -0,0 +1,270 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 数据统计
+ */
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	datacubeUserSummaryPath      = "https://api.weixin.qq.com/datacube/getusersummary"
+	datacubeUserCumulatePath     = "https://api.weixin.qq.com/datacube/getusercumulate"
+	datacubeArticleSummaryPath   = "https://api.weixin.qq.com/datacube/getarticlesummary"
+	datacubeArticleTotalPath     = "https://api.weixin.qq.com/datacube/getarticletotal"
+	datacubeUserReadPath         = "https://api.weixin.qq.com/datacube/getuserread"
+	datacubeUserSharePath        = "https://api.weixin.qq.com/datacube/getusershare"
+	datacubeUpstreamMsgPath      = "https://api.weixin.qq.com/datacube/getupstreammsg"
+	datacubeInterfaceSummaryPath = "https://api.weixin.qq.com/datacube/getinterfacesummary"
+	datacubeDateLayout           = "2006-01-02"
+)
+
+// max days of date range by api
+const (
+	maxUserSummaryDays      = 7
+	maxArticleSummaryDays   = 1
+	maxArticleTotalDays     = 1
+	maxUserReadDays         = 3
+	maxUserShareDays        = 7
+	maxUpstreamMsgDays      = 7
+	maxInterfaceSummaryDays = 30
+)
+
+// UserSummary of users increased and decreased by source.
+type UserSummary struct {
+	RefDate    string `json:"ref_date"`
+	UserSource int32  `json:"user_source"`
+	NewUser    int64  `json:"new_user"`
+	CancelUser int64  `json:"cancel_user"`
+}
+
+// UserCumulate is total of users.
+type UserCumulate struct {
+	RefDate      string `json:"ref_date"`
+	CumulateUser int64  `json:"cumulate_user"`
+}
+
+// ArticleStat of reading, sharing and favorite.
+type ArticleStat struct {
+	IntPageReadUser  int64 `json:"int_page_read_user"`
+	IntPageReadCount int64 `json:"int_page_read_count"`
+	OriPageReadUser  int64 `json:"ori_page_read_user"`
+	OriPageReadCount int64 `json:"ori_page_read_count"`
+	ShareUser        int64 `json:"share_user"`
+	ShareCount       int64 `json:"share_count"`
+	AddToFavUser     int64 `json:"add_to_fav_user"`
+	AddToFavCount    int64 `json:"add_to_fav_count"`
+}
+
+// ArticleSummary of articles sent on the day.
+type ArticleSummary struct {
+	RefDate string `json:"ref_date"`
+	MsgID   string `json:"msgid"` // msgdataid_index
+	Title   string `json:"title"`
+	ArticleStat
+}
+
+// ArticleTotal of articles sent on the day, with stats of following days.
+type ArticleTotal struct {
+	RefDate string          `json:"ref_date"`
+	MsgID   string          `json:"msgid"`
+	Title   string          `json:"title"`
+	Details []ArticleDetail `json:"details"`
+}
+
+type ArticleDetail struct {
+	StatDate   string `json:"stat_date"`
+	TargetUser int64  `json:"target_user"`
+	ArticleStat
+}
+
+// UserRead of all articles by day.
+type UserRead struct {
+	RefDate    string `json:"ref_date"`
+	UserSource int32  `json:"user_source"`
+	ArticleStat
+}
+
+// UserShare of articles by day and scene.
+type UserShare struct {
+	RefDate    string `json:"ref_date"`
+	ShareScene int32  `json:"share_scene"`
+	ShareCount int64  `json:"share_count"`
+	ShareUser  int64  `json:"share_user"`
+}
+
+// UpstreamMsg of messages sent by users by day and type.
+type UpstreamMsg struct {
+	RefDate  string `json:"ref_date"`
+	MsgType  int32  `json:"msg_type"`
+	MsgUser  int64  `json:"msg_user"`
+	MsgCount int64  `json:"msg_count"`
+}
+
+// InterfaceSummary of message callbacks by day.
+type InterfaceSummary struct {
+	RefDate       string `json:"ref_date"`
+	CallbackCount int64  `json:"callback_count"`
+	FailCount     int64  `json:"fail_count"`
+	TotalTimeCost int64  `json:"total_time_cost"` // milliseconds
+	MaxTimeCost   int64  `json:"max_time_cost"`
+}
+
+// GetUserSummary from begin to end inclusive, split into windows of 7 days.
+// https://api.weixin.qq.com/datacube/getusersummary?access_token=ACCESS_TOKEN
+func (mp *MP) GetUserSummary(begin, end time.Time, timeout int) (list []UserSummary, err error) {
+	err = mp.datacube(datacubeUserSummaryPath, maxUserSummaryDays, begin, end, timeout, func(data json.RawMessage) error {
+		var l []UserSummary
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	return
+}
+
+// GetUserCumulate from begin to end inclusive, split into windows of 7 days.
+// https://api.weixin.qq.com/datacube/getusercumulate?access_token=ACCESS_TOKEN
+func (mp *MP) GetUserCumulate(begin, end time.Time, timeout int) (list []UserCumulate, err error) {
+	err = mp.datacube(datacubeUserCumulatePath, maxUserSummaryDays, begin, end, timeout, func(data json.RawMessage) error {
+		var l []UserCumulate
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	return
+}
+
+// GetArticleSummary from begin to end inclusive, requested day by day.
+// https://api.weixin.qq.com/datacube/getarticlesummary?access_token=ACCESS_TOKEN
+func (mp *MP) GetArticleSummary(begin, end time.Time, timeout int) (list []ArticleSummary, err error) {
+	err = mp.datacube(datacubeArticleSummaryPath, maxArticleSummaryDays, begin, end, timeout, func(data json.RawMessage) error {
+		var l []ArticleSummary
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	return
+}
+
+// GetArticleTotal from begin to end inclusive, requested day by day.
+// https://api.weixin.qq.com/datacube/getarticletotal?access_token=ACCESS_TOKEN
+func (mp *MP) GetArticleTotal(begin, end time.Time, timeout int) (list []ArticleTotal, err error) {
+	err = mp.datacube(datacubeArticleTotalPath, maxArticleTotalDays, begin, end, timeout, func(data json.RawMessage) error {
+		var l []ArticleTotal
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	return
+}
+
+// GetUserRead from begin to end inclusive, split into windows of 3 days.
+// https://api.weixin.qq.com/datacube/getuserread?access_token=ACCESS_TOKEN
+func (mp *MP) GetUserRead(begin, end time.Time, timeout int) (list []UserRead, err error) {
+	err = mp.datacube(datacubeUserReadPath, maxUserReadDays, begin, end, timeout, func(data json.RawMessage) error {
+		var l []UserRead
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	return
+}
+
+// GetUserShare from begin to end inclusive, split into windows of 7 days.
+// https://api.weixin.qq.com/datacube/getusershare?access_token=ACCESS_TOKEN
+func (mp *MP) GetUserShare(begin, end time.Time, timeout int) (list []UserShare, err error) {
+	err = mp.datacube(datacubeUserSharePath, maxUserShareDays, begin, end, timeout, func(data json.RawMessage) error {
+		var l []UserShare
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	return
+}
+
+// GetUpstreamMsg from begin to end inclusive, split into windows of 7 days.
+// https://api.weixin.qq.com/datacube/getupstreammsg?access_token=ACCESS_TOKEN
+func (mp *MP) GetUpstreamMsg(begin, end time.Time, timeout int) (list []UpstreamMsg, err error) {
+	err = mp.datacube(datacubeUpstreamMsgPath, maxUpstreamMsgDays, begin, end, timeout, func(data json.RawMessage) error {
+		var l []UpstreamMsg
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	return
+}
+
+// GetInterfaceSummary from begin to end inclusive, split into windows of 30 days.
+// https://api.weixin.qq.com/datacube/getinterfacesummary?access_token=ACCESS_TOKEN
+func (mp *MP) GetInterfaceSummary(begin, end time.Time, timeout int) (list []InterfaceSummary, err error) {
+	err = mp.datacube(datacubeInterfaceSummaryPath, maxInterfaceSummaryDays, begin, end, timeout, func(data json.RawMessage) error {
+		var l []InterfaceSummary
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	return
+}
+
+// datacube requests windows of date range in order, and appends list of each window by appendList.
+// Lists of windows fetched are appended already if error occurred.
+func (mp *MP) datacube(path string, maxDays int, begin, end time.Time, timeout int, appendList func(data json.RawMessage) error) error {
+	return fetchWindows(begin, end, maxDays, func(begin, end time.Time) (json.RawMessage, error) {
+		body := struct {
+			BeginDate string `json:"begin_date"`
+			EndDate   string `json:"end_date"`
+		}{begin.Format(datacubeDateLayout), end.Format(datacubeDateLayout)}
+		var resp struct {
+			List json.RawMessage `json:"list"`
+		}
+		err := mp.postJSON(path, body, &resp, timeout)
+		return resp.List, err
+	}, appendList)
+}
+
+// fetchWindows calls fetch with windows of date range in order, appending lists fetched by appendList.
+func fetchWindows(begin, end time.Time, maxDays int, fetch func(begin, end time.Time) (json.RawMessage, error), appendList func(data json.RawMessage) error) error {
+	windows, err := dateWindows(begin, end, maxDays)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range windows {
+		data, err := fetch(w[0], w[1])
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		err = appendList(data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dateWindows splits dates from begin to end inclusive into windows of maxDays at most.
+func dateWindows(begin, end time.Time, maxDays int) ([][2]time.Time, error) {
+	begin = time.Date(begin.Year(), begin.Month(), begin.Day(), 0, 0, 0, 0, begin.Location())
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, end.Location())
+	if end.Before(begin) {
+		return nil, wx.ParameterError{InvalidParameter: "date: " + begin.Format(datacubeDateLayout) + " after " + end.Format(datacubeDateLayout)}
+	}
+
+	var windows [][2]time.Time
+	for !begin.After(end) {
+		last := begin.AddDate(0, 0, maxDays-1)
+		if last.After(end) {
+			last = end
+		}
+		windows = append(windows, [2]time.Time{begin, last})
+		begin = last.AddDate(0, 0, 1)
+	}
+	return windows, nil
+}
@@ -0,0 +1,102 @@
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDateWindows(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2018, 1, d, 15, 4, 5, 0, time.UTC) }
+	for i, c := range []struct {
+		begin, end int
+		maxDays    int
+		windows    string
+	}{
+		{1, 1, 1, "[01-01 01-01]"},
+		{1, 3, 1, "[01-01 01-01] [01-02 01-02] [01-03 01-03]"},
+		{1, 7, 7, "[01-01 01-07]"},
+		{1, 16, 7, "[01-01 01-07] [01-08 01-14] [01-15 01-16]"},
+		{1, 31, 30, "[01-01 01-30] [01-31 01-31]"},
+	} {
+		windows, err := dateWindows(day(c.begin), day(c.end), c.maxDays)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s string
+		for j, w := range windows {
+			if j > 0 {
+				s += " "
+			}
+			s += "[" + w[0].Format("01-02") + " " + w[1].Format("01-02") + "]"
+		}
+		if s != c.windows {
+			t.Errorf("case %d: windows %s, expect %s", i, s, c.windows)
+		}
+	}
+
+	if _, err := dateWindows(day(2), day(1), 7); err == nil {
+		t.Error("expect invalid date range")
+	}
+}
+
+func TestFetchWindows(t *testing.T) {
+	begin := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	var list []UserSummary
+	fetch := func(begin, end time.Time) (json.RawMessage, error) {
+		var items []string
+		for d := begin; !d.After(end); d = d.AddDate(0, 0, 1) {
+			items = append(items, fmt.Sprintf(`{"ref_date":%q,"new_user":%d}`, d.Format(datacubeDateLayout), d.Day()))
+		}
+		data := "["
+		for i, item := range items {
+			if i > 0 {
+				data += ","
+			}
+			data += item
+		}
+		return json.RawMessage(data + "]"), nil
+	}
+	var windows int
+	err := fetchWindows(begin, begin.AddDate(0, 0, 9), 7, fetch, func(data json.RawMessage) error {
+		windows++
+		var l []UserSummary
+		err := json.Unmarshal(data, &l)
+		list = append(list, l...)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 10 || list[0].RefDate != "2018-01-01" || list[9].RefDate != "2018-01-10" || list[9].NewUser != 10 {
+		t.Errorf("merged list: %+v", list)
+	}
+	if windows != 2 {
+		t.Errorf("%d windows appended", windows)
+	}
+}
+
+func TestGetUserCumulate(t *testing.T) {
+	api, stop := serveFakeAPI(func(r *apiRequest) string {
+		var body struct {
+			BeginDate string `json:"begin_date"`
+		}
+		json.Unmarshal(r.Body, &body)
+		return fmt.Sprintf(`{"list":[{"ref_date":%q,"cumulate_user":1}]}`, body.BeginDate)
+	})
+	defer stop()
+
+	begin := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	list, err := newTestMP().GetUserCumulate(begin, begin.AddDate(0, 0, 9), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].RefDate != "2018-01-01" || list[1].RefDate != "2018-01-08" {
+		t.Errorf("merged list: %+v", list)
+	}
+	requests := api.served(datacubeUserCumulatePath)
+	if len(requests) != 2 || string(requests[1].Body) != `{"begin_date":"2018-01-08","end_date":"2018-01-10"}` {
+		t.Errorf("%d requests", len(requests))
+	}
+}
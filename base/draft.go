@@ -0,0 +1,340 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 草稿箱
+ * https://mp.weixin.qq.com/wiki/ 发布能力
+ */
+
+import (
+	"fmt"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	draftAddPath          = "https://api.weixin.qq.com/cgi-bin/draft/add"
+	draftGetPath          = "https://api.weixin.qq.com/cgi-bin/draft/get"
+	draftUpdatePath       = "https://api.weixin.qq.com/cgi-bin/draft/update"
+	draftDeletePath       = "https://api.weixin.qq.com/cgi-bin/draft/delete"
+	draftCountPath        = "https://api.weixin.qq.com/cgi-bin/draft/count"
+	draftBatchGetPath     = "https://api.weixin.qq.com/cgi-bin/draft/batchget"
+	publishSubmitPath     = "https://api.weixin.qq.com/cgi-bin/freepublish/submit"
+	publishGetPath        = "https://api.weixin.qq.com/cgi-bin/freepublish/get"
+	publishDeletePath     = "https://api.weixin.qq.com/cgi-bin/freepublish/delete"
+	publishGetArticlePath = "https://api.weixin.qq.com/cgi-bin/freepublish/getarticle"
+	publishBatchGetPath   = "https://api.weixin.qq.com/cgi-bin/freepublish/batchget"
+	MaxNewsBatchCount     = 20
+)
+
+// DraftArticle of draft, cropping of cover is in coordinates from 0 to 1, such as "0_0_1_0.5".
+type DraftArticle struct {
+	NewsArticle
+	PicCrop2351 string `json:"pic_crop_235_1,omitempty"`
+	PicCrop11   string `json:"pic_crop_1_1,omitempty"`
+}
+
+// AddDraft of articles, returns media id of draft.
+// https://api.weixin.qq.com/cgi-bin/draft/add?access_token=ACCESS_TOKEN
+func (mp *MP) AddDraft(articles []DraftArticle, timeout int) (mediaID string, err error) {
+	if len(articles) == 0 || len(articles) > MaxNewsArticles {
+		return "", wx.ParameterError{InvalidParameter: fmt.Sprintf("articles: %d articles, expect 1 to %d", len(articles), MaxNewsArticles)}
+	}
+
+	body := struct {
+		Articles []DraftArticle `json:"articles"`
+	}{articles}
+
+	var resp struct {
+		MediaID string `json:"media_id"`
+	}
+	err = mp.postJSON(draftAddPath, body, &resp, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.MediaID, nil
+}
+
+// GetDraft returns articles of draft.
+// https://api.weixin.qq.com/cgi-bin/draft/get?access_token=ACCESS_TOKEN
+func (mp *MP) GetDraft(mediaID string, timeout int) (items []NewsItem, err error) {
+	if len(mediaID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+
+	body := struct {
+		MediaID string `json:"media_id"`
+	}{mediaID}
+
+	var resp struct {
+		NewsItem []NewsItem `json:"news_item"`
+	}
+	err = mp.postJSON(draftGetPath, body, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.NewsItem, nil
+}
+
+// UpdateDraft replaces article of draft, index starts from 0.
+// https://api.weixin.qq.com/cgi-bin/draft/update?access_token=ACCESS_TOKEN
+func (mp *MP) UpdateDraft(mediaID string, index int, article *DraftArticle, timeout int) error {
+	if len(mediaID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+	if index < 0 || index >= MaxNewsArticles {
+		return wx.ParameterError{InvalidParameter: "index"}
+	}
+
+	body := struct {
+		MediaID  string        `json:"media_id"`
+		Index    int           `json:"index"`
+		Articles *DraftArticle `json:"articles"`
+	}{mediaID, index, article}
+
+	return mp.postJSON(draftUpdatePath, body, nil, timeout)
+}
+
+// DeleteDraft by media id.
+// https://api.weixin.qq.com/cgi-bin/draft/delete?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteDraft(mediaID string, timeout int) error {
+	if len(mediaID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+
+	body := struct {
+		MediaID string `json:"media_id"`
+	}{mediaID}
+
+	return mp.postJSON(draftDeletePath, body, nil, timeout)
+}
+
+// GetDraftCount returns total of drafts.
+// https://api.weixin.qq.com/cgi-bin/draft/count?access_token=ACCESS_TOKEN
+func (mp *MP) GetDraftCount(timeout int) (count int, err error) {
+	var resp struct {
+		TotalCount int `json:"total_count"`
+	}
+	err = mp.getJSON(draftCountPath, &resp, timeout)
+	if err != nil {
+		return 0, err
+	}
+	return resp.TotalCount, nil
+}
+
+// NewsBatchItem of drafts or articles published, with MediaID or ArticleID respectively.
+type NewsBatchItem struct {
+	MediaID   string `json:"media_id,omitempty"`
+	ArticleID string `json:"article_id,omitempty"`
+	Content   struct {
+		NewsItem []NewsItem `json:"news_item"`
+	} `json:"content"`
+	UpdateTime int64 `json:"update_time"`
+}
+
+// NewsBatch is a page of drafts or articles published.
+type NewsBatch struct {
+	TotalCount int             `json:"total_count"`
+	ItemCount  int             `json:"item_count"`
+	Item       []NewsBatchItem `json:"item"`
+}
+
+// BatchGetDrafts returns count drafts from offset, no more than 20 at a time,
+// content of articles is omitted if noContent.
+// https://api.weixin.qq.com/cgi-bin/draft/batchget?access_token=ACCESS_TOKEN
+func (mp *MP) BatchGetDrafts(offset, count int, noContent bool, timeout int) (*NewsBatch, error) {
+	return mp.batchGetNews(draftBatchGetPath, offset, count, noContent, timeout)
+}
+
+// BatchGetPublished returns count articles published from offset, no more than 20 at a time,
+// content of articles is omitted if noContent.
+// https://api.weixin.qq.com/cgi-bin/freepublish/batchget?access_token=ACCESS_TOKEN
+func (mp *MP) BatchGetPublished(offset, count int, noContent bool, timeout int) (*NewsBatch, error) {
+	return mp.batchGetNews(publishBatchGetPath, offset, count, noContent, timeout)
+}
+
+func (mp *MP) batchGetNews(path string, offset, count int, noContent bool, timeout int) (batch *NewsBatch, err error) {
+	if offset < 0 {
+		return nil, wx.ParameterError{InvalidParameter: "offset"}
+	}
+	if count <= 0 || count > MaxNewsBatchCount {
+		return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("count: %d, expect 1 to %d", count, MaxNewsBatchCount)}
+	}
+
+	body := struct {
+		Offset    int `json:"offset"`
+		Count     int `json:"count"`
+		NoContent int `json:"no_content"`
+	}{Offset: offset, Count: count}
+	if noContent {
+		body.NoContent = 1
+	}
+
+	batch = new(NewsBatch)
+	err = mp.postJSON(path, body, batch, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// NewsBatchIterator iterates drafts or articles published page by page.
+//
+//	it := mp.DraftIterator(false, 0)
+//	for it.Next() {
+//		item := it.Item()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type NewsBatchIterator struct {
+	fetch func(offset, count int) (*NewsBatch, error)
+	pager offsetPager
+	page  []NewsBatchItem
+	item  NewsBatchItem
+}
+
+// DraftIterator iterates drafts.
+func (mp *MP) DraftIterator(noContent bool, timeout int) *NewsBatchIterator {
+	return &NewsBatchIterator{
+		fetch: func(offset, count int) (*NewsBatch, error) {
+			return mp.BatchGetDrafts(offset, count, noContent, timeout)
+		},
+	}
+}
+
+// PublishedIterator iterates articles published.
+func (mp *MP) PublishedIterator(noContent bool, timeout int) *NewsBatchIterator {
+	return &NewsBatchIterator{
+		fetch: func(offset, count int) (*NewsBatch, error) {
+			return mp.BatchGetPublished(offset, count, noContent, timeout)
+		},
+	}
+}
+
+// Next moves to the next item, false if no more or error occurred.
+func (it *NewsBatchIterator) Next() bool {
+	for len(it.page) == 0 {
+		ok := it.pager.next(func(offset int) (int, int, error) {
+			batch, err := it.fetch(offset, MaxNewsBatchCount)
+			if err != nil {
+				return 0, 0, err
+			}
+			it.page = batch.Item
+			return len(batch.Item), batch.TotalCount, nil
+		})
+		if !ok {
+			return false
+		}
+	}
+
+	it.item, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Item the iterator is at.
+func (it *NewsBatchIterator) Item() NewsBatchItem {
+	return it.item
+}
+
+// Err occurred while fetching pages.
+func (it *NewsBatchIterator) Err() error {
+	return it.pager.err
+}
+
+// PublishResult of submitting draft, result is reported in PUBLISHJOBFINISH event.
+type PublishResult struct {
+	PublishID string `json:"publish_id"`
+	MsgDataID int64  `json:"msg_data_id"` // for statistics and comments
+}
+
+// SubmitPublish of draft.
+// https://api.weixin.qq.com/cgi-bin/freepublish/submit?access_token=ACCESS_TOKEN
+func (mp *MP) SubmitPublish(mediaID string, timeout int) (result *PublishResult, err error) {
+	if len(mediaID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+
+	body := struct {
+		MediaID string `json:"media_id"`
+	}{mediaID}
+
+	result = new(PublishResult)
+	err = mp.postJSON(publishSubmitPath, body, result, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PublishStatus of publishing job, status is one of message.Publish* constants,
+// FailIdx lists index of articles failed, starting from 1.
+type PublishStatus struct {
+	PublishID     string `json:"publish_id"`
+	PublishStatus int32  `json:"publish_status"`
+	ArticleID     string `json:"article_id"`
+	ArticleDetail struct {
+		Count int32 `json:"count"`
+		Item  []struct {
+			Idx        int32  `json:"idx"`
+			ArticleURL string `json:"article_url"`
+		} `json:"item"`
+	} `json:"article_detail"`
+	FailIdx []int32 `json:"fail_idx"`
+}
+
+// GetPublishStatus of publishing job.
+// https://api.weixin.qq.com/cgi-bin/freepublish/get?access_token=ACCESS_TOKEN
+func (mp *MP) GetPublishStatus(publishID string, timeout int) (status *PublishStatus, err error) {
+	if len(publishID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "publishID"}
+	}
+
+	body := struct {
+		PublishID string `json:"publish_id"`
+	}{publishID}
+
+	status = new(PublishStatus)
+	err = mp.postJSON(publishGetPath, body, status, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// DeletePublished deletes article published, index starts from 1, or 0 to delete all articles.
+// https://api.weixin.qq.com/cgi-bin/freepublish/delete?access_token=ACCESS_TOKEN
+func (mp *MP) DeletePublished(articleID string, index int, timeout int) error {
+	if len(articleID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "articleID"}
+	}
+	if index < 0 || index > MaxNewsArticles {
+		return wx.ParameterError{InvalidParameter: "index"}
+	}
+
+	body := struct {
+		ArticleID string `json:"article_id"`
+		Index     int    `json:"index,omitempty"`
+	}{articleID, index}
+
+	return mp.postJSON(publishDeletePath, body, nil, timeout)
+}
+
+// GetPublishedArticle returns articles published.
+// https://api.weixin.qq.com/cgi-bin/freepublish/getarticle?access_token=ACCESS_TOKEN
+func (mp *MP) GetPublishedArticle(articleID string, timeout int) (items []NewsItem, err error) {
+	if len(articleID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "articleID"}
+	}
+
+	body := struct {
+		ArticleID string `json:"article_id"`
+	}{articleID}
+
+	var resp struct {
+		NewsItem []NewsItem `json:"news_item"`
+	}
+	err = mp.postJSON(publishGetArticlePath, body, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.NewsItem, nil
+}
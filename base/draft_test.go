@@ -0,0 +1,67 @@
+package base
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDraftArticle(t *testing.T) {
+	a := DraftArticle{
+		NewsArticle: NewsArticle{ThumbMediaID: "THUMB_MEDIA_ID", Title: "TITLE", Content: "CONTENT"},
+		PicCrop2351: "0.1945_0_1_0.5236",
+	}
+	data, _ := json.Marshal(a)
+	for _, s := range []string{`"title":"TITLE"`, `"thumb_media_id":"THUMB_MEDIA_ID"`, `"pic_crop_235_1":"0.1945_0_1_0.5236"`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("marshaled %s, expect %s", data, s)
+		}
+	}
+	if strings.Contains(string(data), "pic_crop_1_1") {
+		t.Errorf("marshaled %s, expect no pic_crop_1_1", data)
+	}
+}
+
+func TestNewsBatchIterator(t *testing.T) {
+	data := `{"total_count":2,"item_count":2,"item":[` +
+		`{"article_id":"ARTICLE_ID","content":{"news_item":[{"title":"TITLE","url":"URL","is_deleted":true}]},"update_time":1645000000},` +
+		`{"article_id":"ARTICLE_ID_2","content":{"news_item":[]},"update_time":1645000001}]}`
+	fetched := 0
+	it := &NewsBatchIterator{
+		fetch: func(offset, count int) (*NewsBatch, error) {
+			fetched++
+			batch := new(NewsBatch)
+			err := json.Unmarshal([]byte(data), batch)
+			return batch, err
+		},
+	}
+
+	var items []NewsBatchItem
+	for it.Next() {
+		items = append(items, it.Item())
+	}
+	if it.Err() != nil || len(items) != 2 || fetched != 1 {
+		t.Fatalf("iterated %d items in %d fetches, error %v", len(items), fetched, it.Err())
+	}
+	if n := items[0].Content.NewsItem; len(n) != 1 || n[0].Title != "TITLE" || !n[0].IsDeleted {
+		t.Errorf("unexpected item: %+v", items[0])
+	}
+}
+
+func TestSubmitPublish(t *testing.T) {
+	api, stop := serveFakeAPI(func(r *apiRequest) string {
+		return `{"errcode":0,"errmsg":"ok","publish_id":"100000001","msg_data_id":2247503051}`
+	})
+	defer stop()
+
+	result, err := newTestMP().SubmitPublish("MEDIA_ID", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PublishID != "100000001" || result.MsgDataID != 2247503051 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if requests := api.served(publishSubmitPath); len(requests) != 1 || string(requests[0].Body) != `{"media_id":"MEDIA_ID"}` {
+		t.Errorf("%d requests", len(requests))
+	}
+}
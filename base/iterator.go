@@ -0,0 +1,118 @@
+package base
+
+// OpenIDList is a page of openids paginated by next_openid.
+type OpenIDList struct {
+	Total int64 `json:"total,omitempty"` // not for all lists
+	Count int64 `json:"count"`
+	Data  struct {
+		OpenID []string `json:"openid"`
+	} `json:"data"`
+	NextOpenID string `json:"next_openid"`
+}
+
+// OpenIDIterator iterates openids page by page,
+// pages are fetched on demand by next_openid.
+//
+//	it := mp.TagUserIterator(tagID, 0)
+//	for it.Next() {
+//		openID := it.OpenID()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type OpenIDIterator struct {
+	fetch  func(nextOpenID string) (*OpenIDList, error)
+	page   []string
+	next   string
+	done   bool
+	openID string
+	err    error
+}
+
+// NewOpenIDIterator iterates openids fetched by fetch, starting after nextOpenID.
+func NewOpenIDIterator(nextOpenID string, fetch func(nextOpenID string) (*OpenIDList, error)) *OpenIDIterator {
+	return &OpenIDIterator{
+		fetch: fetch,
+		next:  nextOpenID,
+	}
+}
+
+// NewOpenIDSliceIterator iterates openids held already.
+func NewOpenIDSliceIterator(openIDs []string) *OpenIDIterator {
+	return NewOpenIDIterator("", func(string) (*OpenIDList, error) {
+		list := &OpenIDList{Count: int64(len(openIDs))}
+		list.Data.OpenID = openIDs
+		return list, nil
+	})
+}
+
+// Next moves to the next openid, false if no more or error occurred.
+func (it *OpenIDIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		list, err := it.fetch(it.next)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = list.Data.OpenID
+		if list.Count == 0 || len(list.NextOpenID) == 0 || list.NextOpenID == it.next {
+			it.done = true
+		}
+		it.next = list.NextOpenID
+	}
+
+	it.openID, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// OpenID the iterator is at.
+func (it *OpenIDIterator) OpenID() string {
+	return it.openID
+}
+
+// Err occurred while fetching pages.
+func (it *OpenIDIterator) Err() error {
+	return it.err
+}
+
+// offsetPager pages through a list by offset, until total reached or an empty page.
+type offsetPager struct {
+	offset int
+	done   bool
+	err    error
+}
+
+// next fetches the page at offset, fetch returns count of items in the page and total of list.
+// False if no more pages or error occurred.
+func (p *offsetPager) next(fetch func(offset int) (count, total int, err error)) bool {
+	if p.done || p.err != nil {
+		return false
+	}
+
+	count, total, err := fetch(p.offset)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	p.offset += count
+	if count == 0 || p.offset >= total {
+		p.done = true
+	}
+	return true
+}
+
+// chunk splits openids into chunks of size at most.
+func chunk(openIDs []string, size int) [][]string {
+	chunks := make([][]string, 0, (len(openIDs)+size-1)/size)
+	for len(openIDs) > size {
+		chunks = append(chunks, openIDs[:size])
+		openIDs = openIDs[size:]
+	}
+	if len(openIDs) > 0 {
+		chunks = append(chunks, openIDs)
+	}
+	return chunks
+}
@@ -0,0 +1,109 @@
+package base
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// pages of openids ends as user/get, with next_openid of the last page as the last openid.
+func fakePages(total, size int, failAt int) func(string) (*OpenIDList, error) {
+	return func(nextOpenID string) (*OpenIDList, error) {
+		start := 0
+		if nextOpenID != "" {
+			fmt.Sscanf(nextOpenID, "openid%d", &start)
+			start++
+		}
+		if start == failAt {
+			return nil, errors.New("fetch failed")
+		}
+
+		list := new(OpenIDList)
+		list.Total = int64(total)
+		for i := start; i < total && i < start+size; i++ {
+			list.Data.OpenID = append(list.Data.OpenID, fmt.Sprintf("openid%d", i))
+		}
+		list.Count = int64(len(list.Data.OpenID))
+		if list.Count > 0 {
+			list.NextOpenID = list.Data.OpenID[list.Count-1]
+		}
+		return list, nil
+	}
+}
+
+func TestOpenIDIterator(t *testing.T) {
+	for _, total := range []int{0, 1, 9, 10, 25} {
+		it := NewOpenIDIterator("", fakePages(total, 10, -1))
+		n := 0
+		for it.Next() {
+			if it.OpenID() != fmt.Sprintf("openid%d", n) {
+				t.Errorf("total %d: unexpected openid %s at %d", total, it.OpenID(), n)
+			}
+			n++
+		}
+		if it.Err() != nil || n != total {
+			t.Errorf("total %d: iterated %d, error %v", total, n, it.Err())
+		}
+	}
+
+	it := NewOpenIDIterator("", fakePages(25, 10, 10))
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if it.Err() == nil || n != 10 {
+		t.Errorf("expect error after 10 openids, got %d, error %v", n, it.Err())
+	}
+}
+
+func TestChunk(t *testing.T) {
+	openIDs := make([]string, 120)
+	chunks := chunk(openIDs, 50)
+	if len(chunks) != 3 || len(chunks[0]) != 50 || len(chunks[2]) != 20 {
+		t.Error("unexpected chunks of 120 openids by 50")
+	}
+	if len(chunk(nil, 50)) != 0 {
+		t.Error("expect no chunk for no openid")
+	}
+}
+
+func TestOffsetPager(t *testing.T) {
+	var offsets []int
+	fetch := func(offset int) (int, int, error) {
+		offsets = append(offsets, offset)
+		if offset == 40 {
+			return 0, 0, errors.New("fetch failed")
+		}
+		return 20, 100, nil
+	}
+
+	var p offsetPager
+	for p.next(fetch) {
+	}
+	if p.err == nil || fmt.Sprint(offsets) != "[0 20 40]" {
+		t.Errorf("fetched at %v, error %v", offsets, p.err)
+	}
+	if p.next(fetch) || len(offsets) != 3 {
+		t.Error("expect no fetch after error")
+	}
+
+	// ends at total, or at an empty page before total
+	for _, c := range []struct {
+		count, total int
+		expect       string
+	}{
+		{20, 50, "[0 20 40]"},
+		{0, 50, "[0]"},
+	} {
+		offsets = nil
+		p = offsetPager{}
+		for p.next(func(offset int) (int, int, error) {
+			offsets = append(offsets, offset)
+			return c.count, c.total, nil
+		}) {
+		}
+		if p.err != nil || fmt.Sprint(offsets) != c.expect {
+			t.Errorf("fetched at %v, expect %s", offsets, c.expect)
+		}
+	}
+}
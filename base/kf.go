@@ -0,0 +1,369 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 消息管理/客服消息
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	kfAccountAddPath      = "https://api.weixin.qq.com/customservice/kfaccount/add"
+	kfAccountUpdatePath   = "https://api.weixin.qq.com/customservice/kfaccount/update"
+	kfAccountDeletePath   = "https://api.weixin.qq.com/customservice/kfaccount/del"
+	kfAccountInvitePath   = "https://api.weixin.qq.com/customservice/kfaccount/inviteworker"
+	kfListPath            = "https://api.weixin.qq.com/cgi-bin/customservice/getkflist"
+	kfOnlineListPath      = "https://api.weixin.qq.com/cgi-bin/customservice/getonlinekflist"
+	kfSessionCreatePath   = "https://api.weixin.qq.com/customservice/kfsession/create"
+	kfSessionClosePath    = "https://api.weixin.qq.com/customservice/kfsession/close"
+	kfSessionGetPath      = "https://api.weixin.qq.com/customservice/kfsession/getsession"
+	kfSessionListPath     = "https://api.weixin.qq.com/customservice/kfsession/getsessionlist"
+	kfSessionWaitCasePath = "https://api.weixin.qq.com/customservice/kfsession/getwaitcase"
+	kfMsgRecordPath       = "https://api.weixin.qq.com/customservice/msgrecord/getmsglist"
+)
+
+// limits of message records
+const (
+	MaxMsgRecordNumber        = 10000
+	maxMsgRecordSpan          = 24 * time.Hour
+	defaultMsgRecordPageLimit = 1000
+)
+
+// SendCustomMessage to user who sent message in 48 hours.
+// https://api.weixin.qq.com/cgi-bin/message/custom/send?access_token=ACCESS_TOKEN
+func (mp *MP) SendCustomMessage(msg *message.CustomMessage, timeout int) error {
+	return message.SendCustomMessage(mp.GetAccessToken(), msg, timeout)
+}
+
+// SendCustomTyping shows or cancels typing status to user.
+// https://api.weixin.qq.com/cgi-bin/message/custom/typing?access_token=ACCESS_TOKEN
+func (mp *MP) SendCustomTyping(openID string, typing bool, timeout int) error {
+	return message.SendCustomTyping(mp.GetAccessToken(), openID, typing, timeout)
+}
+
+// KFAccount of customer service.
+type KFAccount struct {
+	KFAccount        string `json:"kf_account"` // account@wechat_id_of_official_account
+	KFNick           string `json:"kf_nick"`
+	KFID             string `json:"kf_id"`
+	KFHeadImgURL     string `json:"kf_headimgurl"`
+	KFWX             string `json:"kf_wx,omitempty"`              // wechat id bound
+	InviteWX         string `json:"invite_wx,omitempty"`          // wechat id invited
+	InviteExpireTime int64  `json:"invite_expire_time,omitempty"` // unix time
+	InviteStatus     string `json:"invite_status,omitempty"`      // waiting, rejected or expired
+}
+
+// KFOnline is status of kf account online.
+type KFOnline struct {
+	KFAccount    string `json:"kf_account"`
+	Status       int32  `json:"status"` // 1 for web
+	KFID         string `json:"kf_id"`
+	AcceptedCase int32  `json:"accepted_case"` // sessions in process
+}
+
+// AddKFAccount with nickname, kfAccount is account@wechat_id_of_official_account.
+// https://api.weixin.qq.com/customservice/kfaccount/add?access_token=ACCESS_TOKEN
+func (mp *MP) AddKFAccount(kfAccount, nickname string, timeout int) error {
+	return mp.postKFAccount(kfAccountAddPath, kfAccount, nickname, timeout)
+}
+
+// UpdateKFAccount nickname.
+// https://api.weixin.qq.com/customservice/kfaccount/update?access_token=ACCESS_TOKEN
+func (mp *MP) UpdateKFAccount(kfAccount, nickname string, timeout int) error {
+	return mp.postKFAccount(kfAccountUpdatePath, kfAccount, nickname, timeout)
+}
+
+func (mp *MP) postKFAccount(path, kfAccount, nickname string, timeout int) error {
+	if len(kfAccount) <= 0 {
+		return wx.ParameterError{InvalidParameter: "kfAccount"}
+	}
+	if len(nickname) <= 0 {
+		return wx.ParameterError{InvalidParameter: "nickname"}
+	}
+
+	body := struct {
+		KFAccount string `json:"kf_account"`
+		Nickname  string `json:"nickname"`
+	}{kfAccount, nickname}
+
+	return mp.postJSON(path, body, nil, timeout)
+}
+
+// DeleteKFAccount by account.
+// https://api.weixin.qq.com/customservice/kfaccount/del?access_token=ACCESS_TOKEN&kf_account=KFACCOUNT
+func (mp *MP) DeleteKFAccount(kfAccount string, timeout int) error {
+	if len(kfAccount) <= 0 {
+		return wx.ParameterError{InvalidParameter: "kfAccount"}
+	}
+	return mp.getJSON(kfAccountDeletePath, nil, timeout,
+		wx.QueryParameter{Key: "kf_account", Value: kfAccount},
+	)
+}
+
+// InviteKFWorker binds wechat id to kf account, after invitation accepted.
+// https://api.weixin.qq.com/customservice/kfaccount/inviteworker?access_token=ACCESS_TOKEN
+func (mp *MP) InviteKFWorker(kfAccount, inviteWX string, timeout int) error {
+	if len(kfAccount) <= 0 {
+		return wx.ParameterError{InvalidParameter: "kfAccount"}
+	}
+	if len(inviteWX) <= 0 {
+		return wx.ParameterError{InvalidParameter: "inviteWX"}
+	}
+
+	body := struct {
+		KFAccount string `json:"kf_account"`
+		InviteWX  string `json:"invite_wx"`
+	}{kfAccount, inviteWX}
+
+	return mp.postJSON(kfAccountInvitePath, body, nil, timeout)
+}
+
+// GetKFList returns all kf accounts.
+// https://api.weixin.qq.com/cgi-bin/customservice/getkflist?access_token=ACCESS_TOKEN
+func (mp *MP) GetKFList(timeout int) (accounts []KFAccount, err error) {
+	var resp struct {
+		KFList []KFAccount `json:"kf_list"`
+	}
+	err = mp.getJSON(kfListPath, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.KFList, nil
+}
+
+// GetOnlineKFList returns kf accounts online.
+// https://api.weixin.qq.com/cgi-bin/customservice/getonlinekflist?access_token=ACCESS_TOKEN
+func (mp *MP) GetOnlineKFList(timeout int) (online []KFOnline, err error) {
+	var resp struct {
+		KFOnlineList []KFOnline `json:"kf_online_list"`
+	}
+	err = mp.getJSON(kfOnlineListPath, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.KFOnlineList, nil
+}
+
+// KFSession between kf account and user.
+type KFSession struct {
+	KFAccount  string `json:"kf_account,omitempty"`
+	OpenID     string `json:"openid,omitempty"`
+	CreateTime int64  `json:"createtime"`
+}
+
+// KFWaitCase is user waiting for session.
+type KFWaitCase struct {
+	OpenID     string `json:"openid"`
+	LatestTime int64  `json:"latest_time"` // unix time of last message
+}
+
+// CreateKFSession assigns user to kf account, kf account should be online.
+// https://api.weixin.qq.com/customservice/kfsession/create?access_token=ACCESS_TOKEN
+func (mp *MP) CreateKFSession(kfAccount, openID string, timeout int) error {
+	return mp.postKFSession(kfSessionCreatePath, kfAccount, openID, timeout)
+}
+
+// CloseKFSession between kf account and user.
+// https://api.weixin.qq.com/customservice/kfsession/close?access_token=ACCESS_TOKEN
+func (mp *MP) CloseKFSession(kfAccount, openID string, timeout int) error {
+	return mp.postKFSession(kfSessionClosePath, kfAccount, openID, timeout)
+}
+
+func (mp *MP) postKFSession(path, kfAccount, openID string, timeout int) error {
+	if len(kfAccount) <= 0 {
+		return wx.ParameterError{InvalidParameter: "kfAccount"}
+	}
+	if len(openID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "openID"}
+	}
+
+	body := struct {
+		KFAccount string `json:"kf_account"`
+		OpenID    string `json:"openid"`
+	}{kfAccount, openID}
+
+	return mp.postJSON(path, body, nil, timeout)
+}
+
+// GetKFSession of user, KFAccount is empty if user not in session.
+// https://api.weixin.qq.com/customservice/kfsession/getsession?access_token=ACCESS_TOKEN&openid=OPENID
+func (mp *MP) GetKFSession(openID string, timeout int) (session *KFSession, err error) {
+	if len(openID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "openID"}
+	}
+
+	session = new(KFSession)
+	err = mp.getJSON(kfSessionGetPath, session, timeout,
+		wx.QueryParameter{Key: "openid", Value: openID},
+	)
+	if err != nil {
+		return nil, err
+	}
+	session.OpenID = openID
+	return session, nil
+}
+
+// GetKFSessions of kf account.
+// https://api.weixin.qq.com/customservice/kfsession/getsessionlist?access_token=ACCESS_TOKEN&kf_account=KFACCOUNT
+func (mp *MP) GetKFSessions(kfAccount string, timeout int) (sessions []KFSession, err error) {
+	if len(kfAccount) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "kfAccount"}
+	}
+
+	var resp struct {
+		SessionList []KFSession `json:"sessionlist"`
+	}
+	err = mp.getJSON(kfSessionListPath, &resp, timeout,
+		wx.QueryParameter{Key: "kf_account", Value: kfAccount},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.SessionList {
+		resp.SessionList[i].KFAccount = kfAccount
+	}
+	return resp.SessionList, nil
+}
+
+// GetKFWaitCases returns users waiting for session, count is total of waiting users.
+// https://api.weixin.qq.com/customservice/kfsession/getwaitcase?access_token=ACCESS_TOKEN
+func (mp *MP) GetKFWaitCases(timeout int) (count int, cases []KFWaitCase, err error) {
+	var resp struct {
+		Count        int          `json:"count"`
+		WaitCaseList []KFWaitCase `json:"waitcaselist"`
+	}
+	err = mp.getJSON(kfSessionWaitCasePath, &resp, timeout)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.Count, resp.WaitCaseList, nil
+}
+
+// MsgRecord between kf account and user.
+type MsgRecord struct {
+	OpenID   string `json:"openid"`
+	OperCode int32  `json:"opercode"` // 2002 sent by kf account, 2003 received from user
+	Text     string `json:"text"`
+	Time     int64  `json:"time"`
+	Worker   string `json:"worker"` // kf account
+}
+
+// MsgRecordList is a page of records, continued by MsgID.
+type MsgRecordList struct {
+	RecordList []MsgRecord `json:"recordlist"`
+	Number     int         `json:"number"`
+	MsgID      int64       `json:"msgid"`
+}
+
+// GetMsgRecords returns records in [start, end) from msgID, which starts from 1,
+// no more than 24 hours and 10000 records at a time.
+// https://api.weixin.qq.com/customservice/msgrecord/getmsglist?access_token=ACCESS_TOKEN
+func (mp *MP) GetMsgRecords(start, end time.Time, msgID int64, number int, timeout int) (list *MsgRecordList, err error) {
+	if !end.After(start) || end.Sub(start) > maxMsgRecordSpan {
+		return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("time: %s to %s, expect no more than 24 hours", start, end)}
+	}
+	if number <= 0 || number > MaxMsgRecordNumber {
+		return nil, wx.ParameterError{InvalidParameter: "number: " + strconv.Itoa(number)}
+	}
+
+	body := struct {
+		StartTime int64 `json:"starttime"`
+		EndTime   int64 `json:"endtime"`
+		MsgID     int64 `json:"msgid"`
+		Number    int   `json:"number"`
+	}{start.Unix(), end.Unix(), msgID, number}
+
+	list = new(MsgRecordList)
+	err = mp.postJSON(kfMsgRecordPath, body, list, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// MsgRecordIterator iterates records of any time range,
+// split into windows of 24 hours and paged by msgid.
+//
+//	it := mp.MsgRecordIterator(start, end, 0, 0)
+//	for it.Next() {
+//		record := it.Record()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type MsgRecordIterator struct {
+	fetch  func(start, end time.Time, msgID int64, number int) (*MsgRecordList, error)
+	start  time.Time // of current window
+	end    time.Time
+	msgID  int64
+	limit  int
+	page   []MsgRecord
+	record MsgRecord
+	err    error
+}
+
+// MsgRecordIterator iterates records in [start, end), limit records a page, 1000 if zero.
+func (mp *MP) MsgRecordIterator(start, end time.Time, limit int, timeout int) *MsgRecordIterator {
+	return newMsgRecordIterator(start, end, limit, func(start, end time.Time, msgID int64, number int) (*MsgRecordList, error) {
+		return mp.GetMsgRecords(start, end, msgID, number, timeout)
+	})
+}
+
+func newMsgRecordIterator(start, end time.Time, limit int, fetch func(start, end time.Time, msgID int64, number int) (*MsgRecordList, error)) *MsgRecordIterator {
+	if limit <= 0 || limit > MaxMsgRecordNumber {
+		limit = defaultMsgRecordPageLimit
+	}
+	return &MsgRecordIterator{
+		fetch: fetch,
+		start: start,
+		end:   end,
+		msgID: 1,
+		limit: limit,
+	}
+}
+
+// Next moves to the next record, false if no more or error occurred.
+func (it *MsgRecordIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || !it.end.After(it.start) {
+			return false
+		}
+
+		windowEnd := it.start.Add(maxMsgRecordSpan)
+		if windowEnd.After(it.end) {
+			windowEnd = it.end
+		}
+		list, err := it.fetch(it.start, windowEnd, it.msgID, it.limit)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = list.RecordList
+
+		if list.Number < it.limit || list.MsgID <= it.msgID {
+			// window exhausted
+			it.start = windowEnd
+			it.msgID = 1
+		} else {
+			it.msgID = list.MsgID
+		}
+	}
+
+	it.record, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Record the iterator is at.
+func (it *MsgRecordIterator) Record() MsgRecord {
+	return it.record
+}
+
+// Err occurred while fetching pages.
+func (it *MsgRecordIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,46 @@
+package base
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMsgRecordIterator(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	end := start.Add(36 * time.Hour)
+
+	// 5 records in the first day, 2 in the rest
+	records := map[int64][]MsgRecord{
+		start.Unix():                     {{Text: "1"}, {Text: "2"}, {Text: "3"}, {Text: "4"}, {Text: "5"}},
+		start.Add(24 * time.Hour).Unix(): {{Text: "6"}, {Text: "7"}},
+	}
+	var windows []string
+	it := newMsgRecordIterator(start, end, 2, func(s, e time.Time, msgID int64, number int) (*MsgRecordList, error) {
+		if e.Sub(s) > maxMsgRecordSpan {
+			t.Fatal("window longer than 24 hours")
+		}
+		windows = append(windows, e.Sub(s).String())
+		all := records[s.Unix()]
+		from := int(msgID) - 1
+		to := from + number
+		if to > len(all) {
+			to = len(all)
+		}
+		return &MsgRecordList{RecordList: all[from:to], Number: to - from, MsgID: int64(to + 1)}, nil
+	})
+
+	var texts []string
+	for it.Next() {
+		texts = append(texts, it.Record().Text)
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if strings.Join(texts, ",") != "1,2,3,4,5,6,7" {
+		t.Error("records: ", texts)
+	}
+	if strings.Join(windows, ",") != "24h0m0s,24h0m0s,24h0m0s,12h0m0s,12h0m0s" {
+		t.Error("windows: ", windows)
+	}
+}
@@ -0,0 +1,375 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 消息管理/群发接口和原创校验
+ */
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	massUploadNewsPath = "https://api.weixin.qq.com/cgi-bin/media/uploadnews"
+	massSendAllPath    = "https://api.weixin.qq.com/cgi-bin/message/mass/sendall"
+	massSendPath       = "https://api.weixin.qq.com/cgi-bin/message/mass/send"
+	massPreviewPath    = "https://api.weixin.qq.com/cgi-bin/message/mass/preview"
+	massDeletePath     = "https://api.weixin.qq.com/cgi-bin/message/mass/delete"
+	massGetPath        = "https://api.weixin.qq.com/cgi-bin/message/mass/get"
+	massSpeedGetPath   = "https://api.weixin.qq.com/cgi-bin/message/mass/speed/get"
+	massSpeedSetPath   = "https://api.weixin.qq.com/cgi-bin/message/mass/speed/set"
+)
+
+// limits of mass message
+const (
+	MaxNewsArticles    = 8
+	MaxMassOpenIDs     = 10000
+	minMassOpenIDs     = 2
+	maxMassClientMsgID = 64
+	MaxMassSpeed       = 4 // 0 for 800k/min, 1 for 600k/min, 2 for 450k/min, 3 for 300k/min, 4 for 100k/min
+)
+
+// type of mass message
+const (
+	MassMPNews  = "mpnews"
+	MassText    = "text"
+	MassVoice   = "voice"
+	MassImage   = "image"
+	MassMPVideo = "mpvideo"
+	MassWXCard  = "wxcard"
+)
+
+// status of mass message
+const (
+	MassSendSuccess = "SEND_SUCCESS"
+	MassSending     = "SENDING"
+	MassSendFail    = "SEND_FAIL"
+	MassDeleted     = "DELETE"
+)
+
+// NewsArticle of news uploaded for mass message.
+type NewsArticle struct {
+	ThumbMediaID       string `json:"thumb_media_id"`
+	Author             string `json:"author,omitempty"`
+	Title              string `json:"title"`
+	ContentSourceURL   string `json:"content_source_url,omitempty"`
+	Content            string `json:"content"`
+	Digest             string `json:"digest,omitempty"`
+	ShowCoverPic       int32  `json:"show_cover_pic"`
+	NeedOpenComment    int32  `json:"need_open_comment,omitempty"`
+	OnlyFansCanComment int32  `json:"only_fans_can_comment,omitempty"`
+}
+
+// UploadNews for mass message, returns media id valid for 3 days.
+// https://api.weixin.qq.com/cgi-bin/media/uploadnews?access_token=ACCESS_TOKEN
+func (mp *MP) UploadNews(articles []NewsArticle, timeout int) (mediaID string, err error) {
+	if len(articles) == 0 || len(articles) > MaxNewsArticles {
+		return "", wx.ParameterError{InvalidParameter: fmt.Sprintf("articles: %d articles, expect 1 to %d", len(articles), MaxNewsArticles)}
+	}
+
+	body := struct {
+		Articles []NewsArticle `json:"articles"`
+	}{articles}
+
+	var resp struct {
+		MediaID string `json:"media_id"`
+	}
+	err = mp.postJSON(massUploadNewsPath, body, &resp, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.MediaID, nil
+}
+
+// MassMessage with content of MessageType set, built by NewMass* functions.
+type MassMessage struct {
+	MessageType string               `json:"msgtype"`
+	MPNews      *message.CustomMedia `json:"mpnews,omitempty"`
+	Text        *message.CustomText  `json:"text,omitempty"`
+	Voice       *message.CustomMedia `json:"voice,omitempty"`
+	Images      *MassImages          `json:"images,omitempty"`
+	MPVideo     *MassVideo           `json:"mpvideo,omitempty"`
+	WXCard      *MassCard            `json:"wxcard,omitempty"`
+	// 1 to send even if articles are judged as reprint
+	SendIgnoreReprint int32 `json:"send_ignore_reprint,omitempty"`
+	// idempotent key in 24 hours, message with the same id is not sent again
+	ClientMsgID string `json:"clientmsgid,omitempty"`
+}
+
+// MassImages of image message.
+type MassImages struct {
+	MediaIDs           []string `json:"media_ids"`
+	Recommend          string   `json:"recommend,omitempty"`
+	NeedOpenComment    int32    `json:"need_open_comment,omitempty"`
+	OnlyFansCanComment int32    `json:"only_fans_can_comment,omitempty"`
+}
+
+// MassVideo of video message, Title and Description are required sending by openids.
+type MassVideo struct {
+	MediaID     string `json:"media_id"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// MassCard of card message.
+type MassCard struct {
+	CardID string `json:"card_id"`
+}
+
+// NewMassMPNews with media id of news uploaded.
+func NewMassMPNews(mediaID string) *MassMessage {
+	return &MassMessage{MessageType: MassMPNews, MPNews: &message.CustomMedia{MediaID: mediaID}}
+}
+
+// NewMassText message.
+func NewMassText(content string) *MassMessage {
+	return &MassMessage{MessageType: MassText, Text: &message.CustomText{Content: content}}
+}
+
+// NewMassVoice with media id of voice uploaded.
+func NewMassVoice(mediaID string) *MassMessage {
+	return &MassMessage{MessageType: MassVoice, Voice: &message.CustomMedia{MediaID: mediaID}}
+}
+
+// NewMassImages with media ids of images uploaded.
+func NewMassImages(mediaIDs []string, recommend string) *MassMessage {
+	return &MassMessage{MessageType: MassImage, Images: &MassImages{MediaIDs: mediaIDs, Recommend: recommend}}
+}
+
+// NewMassVideo with media id of video uploaded for mass message,
+// title and description are required by SendMassByOpenIDs.
+func NewMassVideo(mediaID, title, description string) *MassMessage {
+	return &MassMessage{MessageType: MassMPVideo, MPVideo: &MassVideo{mediaID, title, description}}
+}
+
+// NewMassCard with card id.
+func NewMassCard(cardID string) *MassMessage {
+	return &MassMessage{MessageType: MassWXCard, WXCard: &MassCard{cardID}}
+}
+
+func (m *MassMessage) validate() error {
+	var ok bool
+	switch m.MessageType {
+	case MassMPNews:
+		ok = m.MPNews != nil && len(m.MPNews.MediaID) > 0
+	case MassText:
+		ok = m.Text != nil && len(m.Text.Content) > 0
+	case MassVoice:
+		ok = m.Voice != nil && len(m.Voice.MediaID) > 0
+	case MassImage:
+		ok = m.Images != nil && len(m.Images.MediaIDs) > 0
+	case MassMPVideo:
+		ok = m.MPVideo != nil && len(m.MPVideo.MediaID) > 0
+	case MassWXCard:
+		ok = m.WXCard != nil && len(m.WXCard.CardID) > 0
+	default:
+		return wx.ParameterError{InvalidParameter: "msgtype: " + m.MessageType}
+	}
+	if !ok {
+		return wx.ParameterError{InvalidParameter: m.MessageType}
+	}
+	if len(m.ClientMsgID) > maxMassClientMsgID {
+		return wx.ParameterError{InvalidParameter: "clientmsgid"}
+	}
+	return nil
+}
+
+// MassResult of mass message sent, MsgID is reported again in MASSSENDJOBFINISH event.
+type MassResult struct {
+	MsgID     int64 `json:"msg_id"`
+	MsgDataID int64 `json:"msg_data_id,omitempty"` // of news, for statistics and comments
+}
+
+// massFilter sends to all followers, or followers with TagID if not IsToAll.
+type massFilter struct {
+	IsToAll bool   `json:"is_to_all"`
+	TagID   *int32 `json:"tag_id,omitempty"`
+}
+
+// SendMassToAll sends message to all followers.
+// https://api.weixin.qq.com/cgi-bin/message/mass/sendall?access_token=ACCESS_TOKEN
+func (mp *MP) SendMassToAll(msg *MassMessage, timeout int) (*MassResult, error) {
+	return mp.sendMassByFilter(&massFilter{IsToAll: true}, msg, timeout)
+}
+
+// SendMassByTag sends message to followers with tag.
+// https://api.weixin.qq.com/cgi-bin/message/mass/sendall?access_token=ACCESS_TOKEN
+func (mp *MP) SendMassByTag(tagID int32, msg *MassMessage, timeout int) (*MassResult, error) {
+	return mp.sendMassByFilter(&massFilter{TagID: &tagID}, msg, timeout)
+}
+
+func (mp *MP) sendMassByFilter(filter *massFilter, msg *MassMessage, timeout int) (*MassResult, error) {
+	err := msg.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	body := struct {
+		Filter *massFilter `json:"filter"`
+		*MassMessage
+	}{filter, msg}
+
+	result := new(MassResult)
+	err = mp.postJSON(massSendAllPath, body, result, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SendMassByOpenIDs sends message to followers in chunks of no more than 10000 openids,
+// results of chunks sent are returned with error of the failed chunk.
+// ClientMsgID of msg is suffixed by index of chunk if more than one chunk.
+// https://api.weixin.qq.com/cgi-bin/message/mass/send?access_token=ACCESS_TOKEN
+func (mp *MP) SendMassByOpenIDs(openIDs []string, msg *MassMessage, timeout int) (results []MassResult, err error) {
+	if len(openIDs) < minMassOpenIDs {
+		return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("openIDs: %d openids, expect at least %d", len(openIDs), minMassOpenIDs)}
+	}
+	err = msg.validate()
+	if err != nil {
+		return nil, err
+	}
+	if msg.MessageType == MassMPVideo && (len(msg.MPVideo.Title) <= 0 || len(msg.MPVideo.Description) <= 0) {
+		return nil, wx.ParameterError{InvalidParameter: "mpvideo: title and description"}
+	}
+
+	chunks := massChunks(openIDs)
+	for i, c := range chunks {
+		m := *msg
+		if len(chunks) > 1 && len(m.ClientMsgID) > 0 {
+			m.ClientMsgID += "_" + strconv.Itoa(i)
+			if len(m.ClientMsgID) > maxMassClientMsgID {
+				return results, wx.ParameterError{InvalidParameter: "clientmsgid: too long to be suffixed"}
+			}
+		}
+
+		body := struct {
+			ToUser []string `json:"touser"`
+			*MassMessage
+		}{c, &m}
+
+		var result MassResult
+		err = mp.postJSON(massSendPath, body, &result, timeout)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// massChunks splits openids into even chunks of no more than MaxMassOpenIDs,
+// so that no chunk falls below minMassOpenIDs.
+func massChunks(openIDs []string) [][]string {
+	n := (len(openIDs) + MaxMassOpenIDs - 1) / MaxMassOpenIDs
+	return chunk(openIDs, (len(openIDs)+n-1)/n)
+}
+
+// PreviewMass sends message to follower for preview.
+// https://api.weixin.qq.com/cgi-bin/message/mass/preview?access_token=ACCESS_TOKEN
+func (mp *MP) PreviewMass(openID string, msg *MassMessage, timeout int) (msgID int64, err error) {
+	if len(openID) <= 0 {
+		return 0, wx.ParameterError{InvalidParameter: "openID"}
+	}
+	body := struct {
+		ToUser string `json:"touser"`
+		*MassMessage
+	}{openID, msg}
+	return mp.previewMass(body, msg, timeout)
+}
+
+// PreviewMassToWXName sends message to follower for preview by wechat id.
+// https://api.weixin.qq.com/cgi-bin/message/mass/preview?access_token=ACCESS_TOKEN
+func (mp *MP) PreviewMassToWXName(wxName string, msg *MassMessage, timeout int) (msgID int64, err error) {
+	if len(wxName) <= 0 {
+		return 0, wx.ParameterError{InvalidParameter: "wxName"}
+	}
+	body := struct {
+		ToWXName string `json:"towxname"`
+		*MassMessage
+	}{wxName, msg}
+	return mp.previewMass(body, msg, timeout)
+}
+
+func (mp *MP) previewMass(body interface{}, msg *MassMessage, timeout int) (msgID int64, err error) {
+	err = msg.validate()
+	if err != nil {
+		return 0, err
+	}
+
+	var resp struct {
+		MsgID int64 `json:"msg_id"`
+	}
+	err = mp.postJSON(massPreviewPath, body, &resp, timeout)
+	if err != nil {
+		return 0, err
+	}
+	return resp.MsgID, nil
+}
+
+// DeleteMass deletes article of mass news message in half an hour after sent,
+// articleIdx starts from 1, or 0 to delete all articles.
+// https://api.weixin.qq.com/cgi-bin/message/mass/delete?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteMass(msgID int64, articleIdx int, timeout int) error {
+	if articleIdx < 0 || articleIdx > MaxNewsArticles {
+		return wx.ParameterError{InvalidParameter: "articleIdx"}
+	}
+
+	body := struct {
+		MsgID      int64 `json:"msg_id"`
+		ArticleIdx int   `json:"article_idx,omitempty"`
+	}{msgID, articleIdx}
+
+	return mp.postJSON(massDeletePath, body, nil, timeout)
+}
+
+// GetMassStatus returns status of mass message, such as MassSendSuccess.
+// https://api.weixin.qq.com/cgi-bin/message/mass/get?access_token=ACCESS_TOKEN
+func (mp *MP) GetMassStatus(msgID int64, timeout int) (status string, err error) {
+	body := struct {
+		MsgID int64 `json:"msg_id"`
+	}{msgID}
+
+	var resp struct {
+		MsgStatus string `json:"msg_status"`
+	}
+	err = mp.postJSON(massGetPath, body, &resp, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.MsgStatus, nil
+}
+
+// MassSpeed of mass message.
+type MassSpeed struct {
+	Speed     int   `json:"speed"`     // level from 0 to 4
+	RealSpeed int64 `json:"realspeed"` // messages per minute, in ten thousand
+}
+
+// GetMassSpeed returns speed of mass message.
+// https://api.weixin.qq.com/cgi-bin/message/mass/speed/get?access_token=ACCESS_TOKEN
+func (mp *MP) GetMassSpeed(timeout int) (speed *MassSpeed, err error) {
+	speed = new(MassSpeed)
+	err = mp.postJSON(massSpeedGetPath, struct{}{}, speed, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return speed, nil
+}
+
+// SetMassSpeed of mass message, level 0 for the fastest.
+// https://api.weixin.qq.com/cgi-bin/message/mass/speed/set?access_token=ACCESS_TOKEN
+func (mp *MP) SetMassSpeed(speed int, timeout int) error {
+	if speed < 0 || speed > MaxMassSpeed {
+		return wx.ParameterError{InvalidParameter: "speed: " + strconv.Itoa(speed)}
+	}
+
+	body := struct {
+		Speed int `json:"speed"`
+	}{speed}
+
+	return mp.postJSON(massSpeedSetPath, body, nil, timeout)
+}
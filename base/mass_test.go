@@ -0,0 +1,88 @@
+package base
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMassChunks(t *testing.T) {
+	for _, c := range []struct {
+		total int
+		sizes string
+	}{
+		{2, "[2]"},
+		{10000, "[10000]"},
+		{10001, "[5001 5000]"},
+		{25000, "[8334 8334 8332]"},
+	} {
+		openIDs := make([]string, c.total)
+		var sizes []int
+		for _, chunk := range massChunks(openIDs) {
+			sizes = append(sizes, len(chunk))
+		}
+		if fmt.Sprint(sizes) != c.sizes {
+			t.Errorf("%d openids: chunks %v, expect %s", c.total, sizes, c.sizes)
+		}
+	}
+}
+
+func TestMassMessage(t *testing.T) {
+	msg := NewMassMPNews("123dsdajkasd231jhksad")
+	msg.SendIgnoreReprint = 1
+	msg.ClientMsgID = "send_tag_2"
+	if err := msg.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	api, stop := serveFakeAPI(func(r *apiRequest) string {
+		return `{"errcode":0,"errmsg":"send job submission success","msg_id":34182,"msg_data_id":206227730}`
+	})
+	defer stop()
+
+	mp := newTestMP()
+	if _, err := mp.SendMassByTag(2, msg, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mp.SendMassByTag(0, NewMassText("hello"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mp.SendMassToAll(NewMassVideo("video", "title", "description"), 0); err != nil {
+		t.Fatal(err)
+	}
+	result, err := mp.SendMassByOpenIDs(openIDs(2), NewMassVideo("video", "title", "description"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].MsgID != 34182 || result[0].MsgDataID != 206227730 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if _, err := mp.SendMassByOpenIDs(openIDs(2), NewMassVideo("video", "", ""), 0); err == nil {
+		t.Error("expect error of video without title")
+	}
+
+	var bodies []string
+	for _, r := range append(api.served(massSendAllPath), api.served(massSendPath)...) {
+		bodies = append(bodies, string(r.Body))
+	}
+	for i, expect := range []string{
+		`{"filter":{"is_to_all":false,"tag_id":2},"msgtype":"mpnews","mpnews":{"media_id":"123dsdajkasd231jhksad"},"send_ignore_reprint":1,"clientmsgid":"send_tag_2"}`,
+		`{"filter":{"is_to_all":false,"tag_id":0},"msgtype":"text","text":{"content":"hello"}}`,
+		`{"filter":{"is_to_all":true},"msgtype":"mpvideo","mpvideo":{"media_id":"video","title":"title","description":"description"}}`,
+		`{"touser":["openid0","openid1"],"msgtype":"mpvideo","mpvideo":{"media_id":"video","title":"title","description":"description"}}`,
+	} {
+		if i >= len(bodies) || bodies[i] != expect {
+			t.Errorf("request %d: expect %s, got %q", i, expect, bodies)
+		}
+	}
+
+	for i, m := range []*MassMessage{
+		NewMassText(""),
+		NewMassImages(nil, ""),
+		{MessageType: "music"},
+		{MessageType: MassVoice, ClientMsgID: "x"},
+	} {
+		if err := m.validate(); err == nil {
+			t.Errorf("case %d: expect invalid", i)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 素材管理
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	mediaUploadPath        = "https://api.weixin.qq.com/cgi-bin/media/upload"
+	mediaGetPath           = "https://api.weixin.qq.com/cgi-bin/media/get"
+	mediaGetJSSDKPath      = "https://api.weixin.qq.com/cgi-bin/media/get/jssdk"
+	mediaUploadImagePath   = "https://api.weixin.qq.com/cgi-bin/media/uploadimg"
+	materialAddPath        = "https://api.weixin.qq.com/cgi-bin/material/add_material"
+	materialAddNewsPath    = "https://api.weixin.qq.com/cgi-bin/material/add_news"
+	materialUpdateNewsPath = "https://api.weixin.qq.com/cgi-bin/material/update_news"
+	materialGetPath        = "https://api.weixin.qq.com/cgi-bin/material/get_material"
+	materialDeletePath     = "https://api.weixin.qq.com/cgi-bin/material/del_material"
+	materialCountPath      = "https://api.weixin.qq.com/cgi-bin/material/get_materialcount"
+	materialBatchGetPath   = "https://api.weixin.qq.com/cgi-bin/material/batchget_material"
+	MaxMaterialBatchCount  = 20
+)
+
+// media type
+const (
+	MediaImage = "image"
+	MediaVoice = "voice"
+	MediaVideo = "video"
+	MediaThumb = "thumb"
+	MediaNews  = "news" // of permanent material only
+)
+
+// Media uploaded temporarily, valid for 3 days.
+type Media struct {
+	Type      string `json:"type"`
+	MediaID   string `json:"media_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// UploadMedia streams content from r as temporary media.
+// https://api.weixin.qq.com/cgi-bin/media/upload?access_token=ACCESS_TOKEN&type=TYPE
+func (mp *MP) UploadMedia(mediaType, filename string, r io.Reader, timeout int) (media *Media, err error) {
+	err = checkMediaType(mediaType, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Media
+		ThumbMediaID string `json:"thumb_media_id"`
+	}
+	err = mp.postMultipart(mediaUploadPath, "media", filename, r, nil, &resp, timeout,
+		wx.QueryParameter{Key: "type", Value: mediaType},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.MediaID) == 0 {
+		resp.MediaID = resp.ThumbMediaID // of thumb
+	}
+	return &resp.Media, nil
+}
+
+// GetMedia copies temporary media into w, video is not downloaded but its url returned.
+// https://api.weixin.qq.com/cgi-bin/media/get?access_token=ACCESS_TOKEN&media_id=MEDIA_ID
+func (mp *MP) GetMedia(mediaID string, w io.Writer, timeout int) (videoURL string, err error) {
+	if len(mediaID) <= 0 {
+		return "", wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+
+	var resp struct {
+		VideoURL string `json:"video_url"`
+	}
+	_, err = mp.download(mediaGetPath, nil, w, &resp, timeout,
+		wx.QueryParameter{Key: "media_id", Value: mediaID},
+	)
+	if err != nil {
+		return "", err
+	}
+	return resp.VideoURL, nil
+}
+
+// GetJSSDKMedia copies high quality voice uploaded by JS-SDK into w, in speex format.
+// https://api.weixin.qq.com/cgi-bin/media/get/jssdk?access_token=ACCESS_TOKEN&media_id=MEDIA_ID
+func (mp *MP) GetJSSDKMedia(mediaID string, w io.Writer, timeout int) error {
+	if len(mediaID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+
+	binary, err := mp.download(mediaGetJSSDKPath, nil, w, nil, timeout,
+		wx.QueryParameter{Key: "media_id", Value: mediaID},
+	)
+	if err != nil {
+		return err
+	}
+	if !binary {
+		return wx.WeshinError{Code: "media", Detail: "no voice content of " + mediaID}
+	}
+	return nil
+}
+
+// UploadArticleImage streams jpg or png image in 1MB from r, returns url to be used in article content.
+// https://api.weixin.qq.com/cgi-bin/media/uploadimg?access_token=ACCESS_TOKEN
+func (mp *MP) UploadArticleImage(filename string, r io.Reader, timeout int) (url string, err error) {
+	var resp struct {
+		URL string `json:"url"`
+	}
+	err = mp.postMultipart(mediaUploadImagePath, "media", filename, r, nil, &resp, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// Material uploaded permanently, URL is only for image.
+type Material struct {
+	MediaID string `json:"media_id"`
+	URL     string `json:"url,omitempty"`
+}
+
+// VideoDescription of video material.
+type VideoDescription struct {
+	Title        string `json:"title"`
+	Introduction string `json:"introduction"`
+}
+
+// AddMaterial streams content from r as permanent material, use AddVideoMaterial for video.
+// https://api.weixin.qq.com/cgi-bin/material/add_material?access_token=ACCESS_TOKEN&type=TYPE
+func (mp *MP) AddMaterial(mediaType, filename string, r io.Reader, timeout int) (material *Material, err error) {
+	if mediaType == MediaVideo {
+		return nil, wx.ParameterError{InvalidParameter: "type: video material requires description"}
+	}
+	err = checkMediaType(mediaType, false)
+	if err != nil {
+		return nil, err
+	}
+	return mp.addMaterial(mediaType, filename, r, nil, timeout)
+}
+
+// AddVideoMaterial streams content from r as permanent video material with description.
+// https://api.weixin.qq.com/cgi-bin/material/add_material?access_token=ACCESS_TOKEN&type=video
+func (mp *MP) AddVideoMaterial(filename string, r io.Reader, desc *VideoDescription, timeout int) (material *Material, err error) {
+	if desc == nil || len(desc.Title) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "description.title"}
+	}
+
+	b, err := json.Marshal(desc)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %s", err)
+	}
+	return mp.addMaterial(MediaVideo, filename, r, map[string]string{"description": string(b)}, timeout)
+}
+
+func (mp *MP) addMaterial(mediaType, filename string, r io.Reader, fields map[string]string, timeout int) (material *Material, err error) {
+	material = new(Material)
+	err = mp.postMultipart(materialAddPath, "media", filename, r, fields, material, timeout,
+		wx.QueryParameter{Key: "type", Value: mediaType},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return material, nil
+}
+
+// AddNews as permanent material, returns media id.
+// https://api.weixin.qq.com/cgi-bin/material/add_news?access_token=ACCESS_TOKEN
+func (mp *MP) AddNews(articles []NewsArticle, timeout int) (mediaID string, err error) {
+	if len(articles) == 0 || len(articles) > MaxNewsArticles {
+		return "", wx.ParameterError{InvalidParameter: fmt.Sprintf("articles: %d articles, expect 1 to %d", len(articles), MaxNewsArticles)}
+	}
+
+	body := struct {
+		Articles []NewsArticle `json:"articles"`
+	}{articles}
+
+	var resp struct {
+		MediaID string `json:"media_id"`
+	}
+	err = mp.postJSON(materialAddNewsPath, body, &resp, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.MediaID, nil
+}
+
+// UpdateNews replaces article of news material, index starts from 0.
+// https://api.weixin.qq.com/cgi-bin/material/update_news?access_token=ACCESS_TOKEN
+func (mp *MP) UpdateNews(mediaID string, index int, article *NewsArticle, timeout int) error {
+	if len(mediaID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+	if index < 0 || index >= MaxNewsArticles {
+		return wx.ParameterError{InvalidParameter: "index"}
+	}
+
+	body := struct {
+		MediaID  string       `json:"media_id"`
+		Index    int          `json:"index"`
+		Articles *NewsArticle `json:"articles"`
+	}{mediaID, index, article}
+
+	return mp.postJSON(materialUpdateNewsPath, body, nil, timeout)
+}
+
+// NewsItem of news material.
+type NewsItem struct {
+	NewsArticle
+	URL       string `json:"url"`
+	ThumbURL  string `json:"thumb_url,omitempty"`
+	IsDeleted bool   `json:"is_deleted,omitempty"` // of article published
+}
+
+// MaterialContent of news or video material.
+type MaterialContent struct {
+	NewsItem    []NewsItem `json:"news_item,omitempty"` // of news
+	Title       string     `json:"title,omitempty"`     // of video
+	Description string     `json:"description,omitempty"`
+	DownURL     string     `json:"down_url,omitempty"`
+}
+
+// GetMaterial copies permanent material into w, while content of news and video is returned instead.
+// https://api.weixin.qq.com/cgi-bin/material/get_material?access_token=ACCESS_TOKEN
+func (mp *MP) GetMaterial(mediaID string, w io.Writer, timeout int) (content *MaterialContent, err error) {
+	if len(mediaID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+
+	body := struct {
+		MediaID string `json:"media_id"`
+	}{mediaID}
+
+	content = new(MaterialContent)
+	binary, err := mp.download(materialGetPath, body, w, content, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if binary {
+		return nil, nil
+	}
+	return content, nil
+}
+
+// DeleteMaterial by media id.
+// https://api.weixin.qq.com/cgi-bin/material/del_material?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteMaterial(mediaID string, timeout int) error {
+	if len(mediaID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "mediaID"}
+	}
+
+	body := struct {
+		MediaID string `json:"media_id"`
+	}{mediaID}
+
+	return mp.postJSON(materialDeletePath, body, nil, timeout)
+}
+
+// MaterialCount of permanent materials by type.
+type MaterialCount struct {
+	VoiceCount int64 `json:"voice_count"`
+	VideoCount int64 `json:"video_count"`
+	ImageCount int64 `json:"image_count"`
+	NewsCount  int64 `json:"news_count"`
+}
+
+// GetMaterialCount returns count of permanent materials.
+// https://api.weixin.qq.com/cgi-bin/material/get_materialcount?access_token=ACCESS_TOKEN
+func (mp *MP) GetMaterialCount(timeout int) (count *MaterialCount, err error) {
+	count = new(MaterialCount)
+	err = mp.getJSON(materialCountPath, count, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+// MaterialItem in material list, Content is only for news.
+type MaterialItem struct {
+	MediaID    string `json:"media_id"`
+	Name       string `json:"name,omitempty"`
+	URL        string `json:"url,omitempty"`
+	UpdateTime int64  `json:"update_time"`
+	Content    *struct {
+		NewsItem []NewsItem `json:"news_item"`
+	} `json:"content,omitempty"`
+}
+
+// MaterialList is a page of materials.
+type MaterialList struct {
+	TotalCount int            `json:"total_count"`
+	ItemCount  int            `json:"item_count"`
+	Item       []MaterialItem `json:"item"`
+}
+
+// BatchGetMaterial returns count materials of type from offset, no more than 20 at a time.
+// https://api.weixin.qq.com/cgi-bin/material/batchget_material?access_token=ACCESS_TOKEN
+func (mp *MP) BatchGetMaterial(mediaType string, offset, count int, timeout int) (list *MaterialList, err error) {
+	err = checkMediaType(mediaType, true)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType == MediaThumb {
+		return nil, wx.ParameterError{InvalidParameter: "type: " + mediaType}
+	}
+	if offset < 0 {
+		return nil, wx.ParameterError{InvalidParameter: "offset"}
+	}
+	if count <= 0 || count > MaxMaterialBatchCount {
+		return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("count: %d, expect 1 to %d", count, MaxMaterialBatchCount)}
+	}
+
+	body := struct {
+		Type   string `json:"type"`
+		Offset int    `json:"offset"`
+		Count  int    `json:"count"`
+	}{mediaType, offset, count}
+
+	list = new(MaterialList)
+	err = mp.postJSON(materialBatchGetPath, body, list, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// MaterialIterator iterates materials of a type page by page.
+//
+//	it := mp.MaterialIterator(MediaNews, 0)
+//	for it.Next() {
+//		item := it.Item()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type MaterialIterator struct {
+	fetch func(offset, count int) (*MaterialList, error)
+	pager offsetPager
+	page  []MaterialItem
+	item  MaterialItem
+}
+
+// MaterialIterator iterates materials of type.
+func (mp *MP) MaterialIterator(mediaType string, timeout int) *MaterialIterator {
+	return &MaterialIterator{
+		fetch: func(offset, count int) (*MaterialList, error) {
+			return mp.BatchGetMaterial(mediaType, offset, count, timeout)
+		},
+	}
+}
+
+// Next moves to the next material, false if no more or error occurred.
+func (it *MaterialIterator) Next() bool {
+	for len(it.page) == 0 {
+		ok := it.pager.next(func(offset int) (int, int, error) {
+			list, err := it.fetch(offset, MaxMaterialBatchCount)
+			if err != nil {
+				return 0, 0, err
+			}
+			it.page = list.Item
+			return len(list.Item), list.TotalCount, nil
+		})
+		if !ok {
+			return false
+		}
+	}
+
+	it.item, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Item the iterator is at.
+func (it *MaterialIterator) Item() MaterialItem {
+	return it.item
+}
+
+// Err occurred while fetching pages.
+func (it *MaterialIterator) Err() error {
+	return it.pager.err
+}
+
+func checkMediaType(mediaType string, news bool) error {
+	switch mediaType {
+	case MediaImage, MediaVoice, MediaVideo, MediaThumb:
+		return nil
+	case MediaNews:
+		if news {
+			return nil
+		}
+	}
+	return wx.ParameterError{InvalidParameter: "type: " + mediaType}
+}
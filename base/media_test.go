@@ -0,0 +1,68 @@
+package base
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+func TestWriteMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	err := writeMultipart(form, "media", "video.mp4", strings.NewReader("video content"), map[string]string{
+		"description": `{"title":"VIDEO_TITLE","introduction":"INTRODUCTION"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, params, _ := mime.ParseMediaType(form.FormDataContentType())
+	r := multipart.NewReader(&buf, params["boundary"])
+	parts := make(map[string]string)
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		data, _ := ioutil.ReadAll(p)
+		parts[p.FormName()+":"+p.FileName()] = string(data)
+	}
+	if parts["media:video.mp4"] != "video content" {
+		t.Error("unexpected file part: ", parts)
+	}
+	if parts["description:"] != `{"title":"VIDEO_TITLE","introduction":"INTRODUCTION"}` {
+		t.Error("unexpected description part: ", parts)
+	}
+}
+
+func TestMaterialIterator(t *testing.T) {
+	var offsets []int
+	it := &MaterialIterator{
+		fetch: func(offset, count int) (*MaterialList, error) {
+			offsets = append(offsets, offset)
+			list := &MaterialList{TotalCount: 45}
+			for i := offset; i < 45 && i < offset+count; i++ {
+				list.Item = append(list.Item, MaterialItem{MediaID: string(rune('A' + i))})
+			}
+			list.ItemCount = len(list.Item)
+			return list, nil
+		},
+	}
+
+	n := 0
+	for it.Next() {
+		if it.Item().MediaID != string(rune('A'+n)) {
+			t.Fatalf("item %d: %s", n, it.Item().MediaID)
+		}
+		n++
+	}
+	if it.Err() != nil || n != 45 {
+		t.Errorf("iterated %d items, error %v", n, it.Err())
+	}
+	if len(offsets) != 3 || offsets[2] != 40 {
+		t.Error("offsets: ", offsets)
+	}
+}
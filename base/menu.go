@@ -0,0 +1,342 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 自定义菜单
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	menuCreatePath            = "https://api.weixin.qq.com/cgi-bin/menu/create"
+	menuGetPath               = "https://api.weixin.qq.com/cgi-bin/menu/get"
+	menuDeletePath            = "https://api.weixin.qq.com/cgi-bin/menu/delete"
+	menuAddConditionalPath    = "https://api.weixin.qq.com/cgi-bin/menu/addconditional"
+	menuDeleteConditionalPath = "https://api.weixin.qq.com/cgi-bin/menu/delconditional"
+	menuTryMatchPath          = "https://api.weixin.qq.com/cgi-bin/menu/trymatch"
+	currentSelfMenuPath       = "https://api.weixin.qq.com/cgi-bin/get_current_selfmenu_info"
+)
+
+// button type
+const (
+	ButtonClick              = "click"
+	ButtonView               = "view"
+	ButtonMiniProgram        = "miniprogram"
+	ButtonScanCodePush       = "scancode_push"
+	ButtonScanCodeWaitMsg    = "scancode_waitmsg"
+	ButtonPicSysPhoto        = "pic_sysphoto"
+	ButtonPicPhotoOrAlbum    = "pic_photo_or_album"
+	ButtonPicWeixin          = "pic_weixin"
+	ButtonLocationSelect     = "location_select"
+	ButtonMediaID            = "media_id"
+	ButtonViewLimited        = "view_limited"
+	ButtonArticleID          = "article_id"
+	ButtonArticleViewLimited = "article_view_limited"
+)
+
+// limits of menu
+const (
+	MaxButtons          = 3
+	MaxSubButtons       = 5
+	maxButtonNameLen    = 16 // bytes
+	maxSubButtonNameLen = 60
+	maxButtonKeyLen     = 128
+	maxButtonURLLen     = 1024
+)
+
+// Button of menu, a top level button either has Type or SubButtons.
+type Button struct {
+	Type       string   `json:"type,omitempty"`
+	Name       string   `json:"name"`
+	Key        string   `json:"key,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	MediaID    string   `json:"media_id,omitempty"`
+	ArticleID  string   `json:"article_id,omitempty"`
+	AppID      string   `json:"appid,omitempty"`    // miniprogram
+	PagePath   string   `json:"pagepath,omitempty"` // miniprogram
+	SubButtons []Button `json:"sub_button,omitempty"`
+}
+
+// Menu of official account, with MatchRule for conditional menu.
+type Menu struct {
+	Buttons   []Button   `json:"button"`
+	MatchRule *MatchRule `json:"matchrule,omitempty"`
+	MenuID    int64      `json:"menuid,omitempty"`
+}
+
+// MatchRule for conditional menu, at least one field should be set.
+type MatchRule struct {
+	TagID              string `json:"tag_id,omitempty"`
+	Sex                string `json:"sex,omitempty"` // 1 for male, 2 for female
+	Country            string `json:"country,omitempty"`
+	Province           string `json:"province,omitempty"`
+	City               string `json:"city,omitempty"`
+	ClientPlatformType string `json:"client_platform_type,omitempty"` // 1 for IOS, 2 for Android, 3 for others
+	Language           string `json:"language,omitempty"`
+}
+
+// UnmarshalJSON accepts numbers as well as strings, as menu/get returns numbers for sex,
+// client_platform_type and tag_id, which is returned as group_id for menus created by group.
+func (r *MatchRule) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		var s string
+		if err = json.Unmarshal(v, &s); err != nil {
+			var n json.Number
+			if err = json.Unmarshal(v, &n); err != nil {
+				return err
+			}
+			s = n.String()
+		}
+		values[key] = s
+	}
+	if len(values["tag_id"]) == 0 {
+		values["tag_id"] = values["group_id"]
+	}
+
+	data, err = json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	type plainRule MatchRule
+	return json.Unmarshal(data, (*plainRule)(r))
+}
+
+// MenuInfo holds default menu and conditional menus.
+type MenuInfo struct {
+	Menu            Menu   `json:"menu"`
+	ConditionalMenu []Menu `json:"conditionalmenu,omitempty"`
+}
+
+// CreateMenu replaces default menu.
+// https://api.weixin.qq.com/cgi-bin/menu/create?access_token=ACCESS_TOKEN
+func (mp *MP) CreateMenu(buttons []Button, timeout int) error {
+	err := ValidateButtons(buttons)
+	if err != nil {
+		return err
+	}
+
+	body := struct {
+		Buttons []Button `json:"button"`
+	}{buttons}
+
+	return mp.postJSON(menuCreatePath, body, nil, timeout)
+}
+
+// GetMenu returns default menu and conditional menus, created by api only.
+// https://api.weixin.qq.com/cgi-bin/menu/get?access_token=ACCESS_TOKEN
+func (mp *MP) GetMenu(timeout int) (info *MenuInfo, err error) {
+	info = new(MenuInfo)
+	err = mp.getJSON(menuGetPath, info, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// DeleteMenu deletes default menu and all conditional menus.
+// https://api.weixin.qq.com/cgi-bin/menu/delete?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteMenu(timeout int) error {
+	return mp.getJSON(menuDeletePath, nil, timeout)
+}
+
+// AddConditionalMenu for users matching menu.MatchRule, returns menu id.
+// https://api.weixin.qq.com/cgi-bin/menu/addconditional?access_token=ACCESS_TOKEN
+func (mp *MP) AddConditionalMenu(menu *Menu, timeout int) (menuID int64, err error) {
+	if menu.MatchRule == nil || *menu.MatchRule == (MatchRule{}) {
+		return 0, wx.ParameterError{InvalidParameter: "matchrule"}
+	}
+	err = ValidateButtons(menu.Buttons)
+	if err != nil {
+		return 0, err
+	}
+
+	body := struct {
+		Buttons   []Button   `json:"button"`
+		MatchRule *MatchRule `json:"matchrule"`
+	}{menu.Buttons, menu.MatchRule}
+
+	var resp struct {
+		MenuID int64 `json:"menuid,string"`
+	}
+	err = mp.postJSON(menuAddConditionalPath, body, &resp, timeout)
+	if err != nil {
+		return 0, err
+	}
+	return resp.MenuID, nil
+}
+
+// DeleteConditionalMenu by menu id.
+// https://api.weixin.qq.com/cgi-bin/menu/delconditional?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteConditionalMenu(menuID int64, timeout int) error {
+	if menuID <= 0 {
+		return wx.ParameterError{InvalidParameter: "menuID"}
+	}
+
+	body := struct {
+		MenuID int64 `json:"menuid,string"`
+	}{menuID}
+
+	return mp.postJSON(menuDeleteConditionalPath, body, nil, timeout)
+}
+
+// TryMatchMenu returns menu buttons shown to user, userID is openid or wechat account.
+// https://api.weixin.qq.com/cgi-bin/menu/trymatch?access_token=ACCESS_TOKEN
+func (mp *MP) TryMatchMenu(userID string, timeout int) (buttons []Button, err error) {
+	if len(userID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "userID"}
+	}
+
+	body := struct {
+		UserID string `json:"user_id"`
+	}{userID}
+
+	var resp struct {
+		Buttons []Button `json:"button"`
+	}
+	err = mp.postJSON(menuTryMatchPath, body, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Buttons, nil
+}
+
+// SelfMenuInfo is the menu in use, set either by api or on mp website.
+type SelfMenuInfo struct {
+	IsMenuOpen   int32 `json:"is_menu_open"`
+	SelfMenuInfo struct {
+		Buttons []SelfMenuButton `json:"button"`
+	} `json:"selfmenu_info"`
+}
+
+type SelfMenuButton struct {
+	Type       string `json:"type,omitempty"`
+	Name       string `json:"name"`
+	Key        string `json:"key,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Value      string `json:"value,omitempty"` // text, image, voice, video of buttons set on mp website
+	SubButtons struct {
+		List []SelfMenuButton `json:"list"`
+	} `json:"sub_button,omitempty"`
+	NewsInfo struct {
+		List []SelfMenuNews `json:"list"`
+	} `json:"news_info,omitempty"`
+}
+
+type SelfMenuNews struct {
+	Title      string `json:"title"`
+	Author     string `json:"author"`
+	Digest     string `json:"digest"`
+	ShowCover  int32  `json:"show_cover"`
+	CoverURL   string `json:"cover_url"`
+	ContentURL string `json:"content_url"`
+	SourceURL  string `json:"source_url"`
+}
+
+// GetCurrentSelfMenu returns menu in use.
+// https://api.weixin.qq.com/cgi-bin/get_current_selfmenu_info?access_token=ACCESS_TOKEN
+func (mp *MP) GetCurrentSelfMenu(timeout int) (info *SelfMenuInfo, err error) {
+	info = new(SelfMenuInfo)
+	err = mp.getJSON(currentSelfMenuPath, info, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ValidateButtons checks buttons against limits of wechat before creating menu.
+func ValidateButtons(buttons []Button) error {
+	if len(buttons) == 0 || len(buttons) > MaxButtons {
+		return wx.ParameterError{InvalidParameter: fmt.Sprintf("button: %d buttons, expect 1 to %d", len(buttons), MaxButtons)}
+	}
+
+	for i, b := range buttons {
+		name := fmt.Sprintf("button[%d]", i)
+		if len(b.SubButtons) == 0 {
+			if err := validateButton(name, &b, maxButtonNameLen); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(b.Name) == 0 || len(b.Name) > maxButtonNameLen {
+			return wx.ParameterError{InvalidParameter: fmt.Sprintf("%s.name: expect 1 to %d bytes", name, maxButtonNameLen)}
+		}
+		if len(b.Type) > 0 {
+			return wx.ParameterError{InvalidParameter: name + ".type: button with sub buttons should have no type"}
+		}
+		if len(b.SubButtons) > MaxSubButtons {
+			return wx.ParameterError{InvalidParameter: fmt.Sprintf("%s.sub_button: %d buttons, expect no more than %d", name, len(b.SubButtons), MaxSubButtons)}
+		}
+		for j, sub := range b.SubButtons {
+			subName := fmt.Sprintf("%s.sub_button[%d]", name, j)
+			if len(sub.SubButtons) > 0 {
+				return wx.ParameterError{InvalidParameter: subName + ".sub_button: only 2 levels of buttons allowed"}
+			}
+			if err := validateButton(subName, &sub, maxSubButtonNameLen); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateButton(name string, b *Button, maxNameLen int) error {
+	if len(b.Name) == 0 || len(b.Name) > maxNameLen {
+		return wx.ParameterError{InvalidParameter: fmt.Sprintf("%s.name: expect 1 to %d bytes", name, maxNameLen)}
+	}
+	if len(b.Key) > maxButtonKeyLen {
+		return wx.ParameterError{InvalidParameter: fmt.Sprintf("%s.key: longer than %d bytes", name, maxButtonKeyLen)}
+	}
+	if len(b.URL) > maxButtonURLLen {
+		return wx.ParameterError{InvalidParameter: fmt.Sprintf("%s.url: longer than %d bytes", name, maxButtonURLLen)}
+	}
+
+	var missing string
+	switch b.Type {
+	case ButtonClick, ButtonScanCodePush, ButtonScanCodeWaitMsg,
+		ButtonPicSysPhoto, ButtonPicPhotoOrAlbum, ButtonPicWeixin, ButtonLocationSelect:
+		if len(b.Key) == 0 {
+			missing = "key"
+		}
+	case ButtonView:
+		if len(b.URL) == 0 {
+			missing = "url"
+		}
+	case ButtonMiniProgram:
+		switch {
+		case len(b.URL) == 0: // for clients not supporting miniprogram
+			missing = "url"
+		case len(b.AppID) == 0:
+			missing = "appid"
+		case len(b.PagePath) == 0:
+			missing = "pagepath"
+		}
+	case ButtonMediaID, ButtonViewLimited:
+		if len(b.MediaID) == 0 {
+			missing = "media_id"
+		}
+	case ButtonArticleID, ButtonArticleViewLimited:
+		if len(b.ArticleID) == 0 {
+			missing = "article_id"
+		}
+	default:
+		return wx.ParameterError{InvalidParameter: fmt.Sprintf("%s.type: unknown type %q", name, b.Type)}
+	}
+	if len(missing) > 0 {
+		return wx.ParameterError{InvalidParameter: fmt.Sprintf("%s.%s: required by %s button", name, missing, b.Type)}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package base
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateButtons(t *testing.T) {
+	valid := []Button{
+		{Type: ButtonClick, Name: "今日歌曲", Key: "V1001_TODAY_MUSIC"},
+		{Name: "菜单", SubButtons: []Button{
+			{Type: ButtonView, Name: "搜索", URL: "http://www.soso.com/"},
+			{Type: ButtonMiniProgram, Name: "wxa", URL: "http://mp.weixin.qq.com", AppID: "wx286b93c14bbf93aa", PagePath: "pages/lunar/index"},
+			{Type: ButtonScanCodePush, Name: "扫码", Key: "rselfmenu_0_1"},
+		}},
+	}
+	if err := ValidateButtons(valid); err != nil {
+		t.Error("valid buttons: ", err)
+	}
+
+	cases := []struct {
+		buttons []Button
+		invalid string
+	}{
+		{nil, "button:"},
+		{[]Button{valid[0], valid[0], valid[0], valid[0]}, "button:"},
+		{[]Button{{Type: ButtonClick, Name: "超过十六个字节的名字", Key: "k"}}, "button[0].name"},
+		{[]Button{{Type: ButtonClick, Name: "click"}}, "button[0].key"},
+		{[]Button{{Type: ButtonClick, Name: "click", Key: strings.Repeat("k", 129)}}, "button[0].key"},
+		{[]Button{{Type: ButtonView, Name: "view"}}, "button[0].url"},
+		{[]Button{{Type: "unknown", Name: "unknown"}}, "button[0].type"},
+		{[]Button{{Type: ButtonClick, Name: "menu", SubButtons: valid[1].SubButtons}}, "button[0].type"},
+		{[]Button{{Name: "menu", SubButtons: []Button{valid[0], valid[0], valid[0], valid[0], valid[0], valid[0]}}}, "button[0].sub_button"},
+		{[]Button{{Name: "menu", SubButtons: []Button{{Type: ButtonMiniProgram, Name: "wxa", URL: "http://mp.weixin.qq.com"}}}}, "button[0].sub_button[0].appid"},
+		{[]Button{{Name: "menu", SubButtons: []Button{valid[1]}}}, "button[0].sub_button[0].sub_button"},
+	}
+	for i, c := range cases {
+		err := ValidateButtons(c.buttons)
+		if err == nil || !strings.Contains(err.Error(), c.invalid) {
+			t.Errorf("case %d: expect invalid %s, got %v", i, c.invalid, err)
+		}
+	}
+}
+
+func TestGetMenu(t *testing.T) {
+	// response of menu/get with a conditional menu created by group
+	_, stop := serveFakeAPI(func(r *apiRequest) string {
+		return `{
+			"menu": {
+				"button": [{"type": "click", "name": "今日歌曲", "key": "V1001_TODAY_MUSIC", "sub_button": []}],
+				"menuid": 208396938
+			},
+			"conditionalmenu": [{
+				"button": [{"type": "click", "name": "今日歌曲", "key": "V1001_TODAY_MUSIC", "sub_button": []}],
+				"matchrule": {"group_id": 2, "sex": 1, "country": "中国", "province": "广东", "city": "广州", "client_platform_type": 2},
+				"menuid": 208396993
+			}]
+		}`
+	})
+	defer stop()
+
+	info, err := newTestMP().GetMenu(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Menu.MenuID != 208396938 || len(info.Menu.Buttons) != 1 || len(info.ConditionalMenu) != 1 {
+		t.Fatalf("unexpected menu: %+v", info)
+	}
+	m := info.ConditionalMenu[0]
+	expect := MatchRule{TagID: "2", Sex: "1", Country: "中国", Province: "广东", City: "广州", ClientPlatformType: "2"}
+	if m.MenuID != 208396993 || m.MatchRule == nil || *m.MatchRule != expect {
+		t.Errorf("unexpected conditional menu: %+v", m.MatchRule)
+	}
+
+	var rule MatchRule
+	if err = json.Unmarshal([]byte(`{"tag_id":"2","sex":"1","language":"zh_CN"}`), &rule); err != nil || rule != (MatchRule{TagID: "2", Sex: "1", Language: "zh_CN"}) {
+		t.Errorf("unmarshaled %+v, error %v", rule, err)
+	}
+	if err = json.Unmarshal([]byte(`{"sex":true}`), &rule); err == nil {
+		t.Error("expect error of invalid sex")
+	}
+}
@@ -0,0 +1,95 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 开始开发/网络检测
+ * https://mp.weixin.qq.com/wiki/ 开始开发/获取微信服务器IP地址
+ */
+
+import (
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	callbackCheckPath = "https://api.weixin.qq.com/cgi-bin/callback/check"
+	apiDomainIPPath   = "https://api.weixin.qq.com/cgi-bin/get_api_domain_ip"
+	callbackIPPath    = "https://api.weixin.qq.com/cgi-bin/getcallbackip"
+)
+
+// action of callback check
+const (
+	CheckAll  = "all"
+	CheckDNS  = "dns"
+	CheckPing = "ping"
+)
+
+// operator of callback check
+const (
+	OperatorDefault  = "DEFAULT" // by network of server
+	OperatorChinaNet = "CHINANET"
+	OperatorUnicom   = "UNICOM"
+	OperatorCAP      = "CAP" // china mobile
+)
+
+// CallbackCheck result from wechat servers to callback url.
+type CallbackCheck struct {
+	DNS []struct {
+		IP           string `json:"ip"`
+		RealOperator string `json:"real_operator"`
+	} `json:"dns"`
+	Ping []struct {
+		IP           string `json:"ip"`
+		FromOperator string `json:"from_operator"`
+		PackageLoss  string `json:"package_loss"`
+		Time         string `json:"time"`
+	} `json:"ping"`
+}
+
+// CheckCallback resolves and pings callback url from wechat servers of operator.
+// https://api.weixin.qq.com/cgi-bin/callback/check?access_token=ACCESS_TOKEN
+func (mp *MP) CheckCallback(action, operator string, timeout int) (result *CallbackCheck, err error) {
+	switch action {
+	case CheckAll, CheckDNS, CheckPing:
+	default:
+		return nil, wx.ParameterError{InvalidParameter: "action: " + action}
+	}
+	switch operator {
+	case OperatorDefault, OperatorChinaNet, OperatorUnicom, OperatorCAP:
+	default:
+		return nil, wx.ParameterError{InvalidParameter: "operator: " + operator}
+	}
+
+	body := struct {
+		Action        string `json:"action"`
+		CheckOperator string `json:"check_operator"`
+	}{action, operator}
+
+	result = new(CallbackCheck)
+	err = mp.postJSON(callbackCheckPath, body, result, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetAPIDomainIP returns ips of api.weixin.qq.com.
+// https://api.weixin.qq.com/cgi-bin/get_api_domain_ip?access_token=ACCESS_TOKEN
+func (mp *MP) GetAPIDomainIP(timeout int) (ips []string, err error) {
+	return mp.getIPList(apiDomainIPPath, timeout)
+}
+
+// GetCallbackIP returns ips and cidrs of wechat servers calling back, for message.IPAllowList.
+// https://api.weixin.qq.com/cgi-bin/getcallbackip?access_token=ACCESS_TOKEN
+func (mp *MP) GetCallbackIP(timeout int) (ips []string, err error) {
+	return mp.getIPList(callbackIPPath, timeout)
+}
+
+func (mp *MP) getIPList(path string, timeout int) (ips []string, err error) {
+	var resp struct {
+		IPList []string `json:"ip_list"`
+	}
+	err = mp.getJSON(path, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.IPList, nil
+}
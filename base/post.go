@@ -0,0 +1,108 @@
+package base
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+// postJSON posts body marshaled with access token of mp,
+// response is unmarshaled into value if not nil.
+func (mp *MP) postJSON(path string, body, value interface{}, timeout int) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %s", err)
+	}
+
+	req := wx.HttpClient{
+		Path:        path,
+		ContentType: "application/json",
+		Timeout:     timeout,
+		Parameters: []wx.QueryParameter{
+			{Key: "access_token", Value: mp.GetAccessToken()},
+		},
+	}
+
+	return req.DoPost(bytes.NewBuffer(b), value)
+}
+
+// getJSON requests path with access token of mp and parameters,
+// response is unmarshaled into value if not nil.
+func (mp *MP) getJSON(path string, value interface{}, timeout int, parameters ...wx.QueryParameter) error {
+	req := wx.HttpClient{
+		Path:    path,
+		Timeout: timeout,
+		Parameters: append([]wx.QueryParameter{
+			{Key: "access_token", Value: mp.GetAccessToken()},
+		}, parameters...),
+	}
+
+	return req.Get(value)
+}
+
+// postMultipart streams content from r as file field named name of multipart form,
+// along with other fields, parameters are added to query with access token of mp.
+func (mp *MP) postMultipart(path, name, filename string, r io.Reader, fields map[string]string, value interface{}, timeout int, parameters ...wx.QueryParameter) error {
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipart(form, name, filename, r, fields))
+	}()
+
+	req := wx.HttpClient{
+		Path:        path,
+		ContentType: form.FormDataContentType(),
+		Timeout:     timeout,
+		Parameters: append([]wx.QueryParameter{
+			{Key: "access_token", Value: mp.GetAccessToken()},
+		}, parameters...),
+	}
+
+	err := req.DoPost(pr, value)
+	pr.Close() // stop writer if request failed before body consumed
+	return err
+}
+
+func writeMultipart(form *multipart.Writer, name, filename string, r io.Reader, fields map[string]string) error {
+	for k, v := range fields {
+		if err := form.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	part, err := form.CreateFormFile(name, filename)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, r)
+	if err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// download copies binary response into w, json response is unmarshaled into value,
+// body is marshaled and posted if not nil, otherwise requested by get.
+func (mp *MP) download(path string, body interface{}, w io.Writer, value interface{}, timeout int, parameters ...wx.QueryParameter) (binary bool, err error) {
+	req := wx.HttpClient{
+		Path:    path,
+		Timeout: timeout,
+		Parameters: append([]wx.QueryParameter{
+			{Key: "access_token", Value: mp.GetAccessToken()},
+		}, parameters...),
+	}
+
+	if body == nil {
+		return req.Download("GET", nil, w, value)
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return false, fmt.Errorf("json.Marshal: %s", err)
+	}
+	req.ContentType = "application/json"
+	return req.Download("POST", bytes.NewBuffer(b), w, value)
+}
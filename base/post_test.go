@@ -0,0 +1,76 @@
+package base
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// apiRequest served by fakeAPI.
+type apiRequest struct {
+	Path  string
+	Query url.Values
+	Body  []byte
+}
+
+// fakeAPI replaces http transport in tests to serve api requests in place of wechat,
+// replying json returned by reply, and recording requests served.
+type fakeAPI struct {
+	reply func(r *apiRequest) string
+
+	mu       sync.Mutex
+	requests []*apiRequest
+}
+
+// serveFakeAPI till returned func called.
+func serveFakeAPI(reply func(r *apiRequest) string) (api *fakeAPI, stop func()) {
+	api = &fakeAPI{reply: reply}
+	transport := http.DefaultTransport
+	http.DefaultTransport = api
+	return api, func() { http.DefaultTransport = transport }
+}
+
+func (api *fakeAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := &apiRequest{
+		Path:  req.URL.Scheme + "://" + req.URL.Host + req.URL.Path,
+		Query: req.URL.Query(),
+	}
+	if req.Body != nil {
+		r.Body, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	api.mu.Lock()
+	api.requests = append(api.requests, r)
+	api.mu.Unlock()
+
+	body := `{"errcode":0,"errmsg":"ok"}`
+	if api.reply != nil {
+		body = api.reply(r)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}, nil
+}
+
+// served requests of path.
+func (api *fakeAPI) served(path string) []*apiRequest {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	var requests []*apiRequest
+	for _, r := range api.requests {
+		if r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+func newTestMP() *MP {
+	return &MP{AppID: appID, Storage: &sampleStorage{token: "ACCESS_TOKEN"}}
+}
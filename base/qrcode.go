@@ -0,0 +1,198 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 账号管理/生成带参数的二维码
+ * https://mp.weixin.qq.com/wiki/ 账号管理/短key托管
+ */
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	qrcodeCreatePath = "https://api.weixin.qq.com/cgi-bin/qrcode/create"
+	qrcodeShowPath   = "https://mp.weixin.qq.com/cgi-bin/showqrcode"
+	shortenGenPath   = "https://api.weixin.qq.com/cgi-bin/shorten/gen"
+	shortenFetchPath = "https://api.weixin.qq.com/cgi-bin/shorten/fetch"
+)
+
+// action of qrcode
+const (
+	QRScene         = "QR_SCENE"
+	QRStrScene      = "QR_STR_SCENE"
+	QRLimitScene    = "QR_LIMIT_SCENE"
+	QRLimitStrScene = "QR_LIMIT_STR_SCENE"
+)
+
+// limits of qrcode and short key
+const (
+	MaxQRExpireSeconds     = 2592000 // 30 days
+	MaxQRLimitSceneID      = 100000
+	maxQRSceneStrLen       = 64
+	MaxShortenExpireSecond = 2592000
+	maxShortenDataLen      = 4096
+)
+
+// QRCode created, show it by QRCodeURL or ShowQRCode with Ticket,
+// or generate image of URL by yourself.
+type QRCode struct {
+	Ticket        string `json:"ticket"`
+	ExpireSeconds int64  `json:"expire_seconds,omitempty"` // of temporary qrcode
+	URL           string `json:"url"`
+}
+
+type qrScene struct {
+	SceneID  int64  `json:"scene_id,omitempty"`
+	SceneStr string `json:"scene_str,omitempty"`
+}
+
+// CreateTempQRCode with non zero scene id, expires in seconds no more than 30 days.
+// https://api.weixin.qq.com/cgi-bin/qrcode/create?access_token=TOKEN
+func (mp *MP) CreateTempQRCode(sceneID uint32, expireSeconds int, timeout int) (*QRCode, error) {
+	if sceneID == 0 {
+		return nil, wx.ParameterError{InvalidParameter: "sceneID: expect non zero"}
+	}
+	return mp.createQRCode(QRScene, qrScene{SceneID: int64(sceneID)}, expireSeconds, timeout)
+}
+
+// CreateTempStrQRCode with scene string, expires in seconds no more than 30 days.
+// https://api.weixin.qq.com/cgi-bin/qrcode/create?access_token=TOKEN
+func (mp *MP) CreateTempStrQRCode(scene string, expireSeconds int, timeout int) (*QRCode, error) {
+	if err := checkSceneStr(scene); err != nil {
+		return nil, err
+	}
+	return mp.createQRCode(QRStrScene, qrScene{SceneStr: scene}, expireSeconds, timeout)
+}
+
+// CreateQRCode permanent with scene id from 1 to 100000.
+// https://api.weixin.qq.com/cgi-bin/qrcode/create?access_token=TOKEN
+func (mp *MP) CreateQRCode(sceneID int, timeout int) (*QRCode, error) {
+	if sceneID < 1 || sceneID > MaxQRLimitSceneID {
+		return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("sceneID: %d, expect 1 to %d", sceneID, MaxQRLimitSceneID)}
+	}
+	return mp.createQRCode(QRLimitScene, qrScene{SceneID: int64(sceneID)}, 0, timeout)
+}
+
+// CreateStrQRCode permanent with scene string.
+// https://api.weixin.qq.com/cgi-bin/qrcode/create?access_token=TOKEN
+func (mp *MP) CreateStrQRCode(scene string, timeout int) (*QRCode, error) {
+	if err := checkSceneStr(scene); err != nil {
+		return nil, err
+	}
+	return mp.createQRCode(QRLimitStrScene, qrScene{SceneStr: scene}, 0, timeout)
+}
+
+func checkSceneStr(scene string) error {
+	if len(scene) == 0 || len(scene) > maxQRSceneStrLen {
+		return wx.ParameterError{InvalidParameter: fmt.Sprintf("scene: %d bytes, expect 1 to %d", len(scene), maxQRSceneStrLen)}
+	}
+	return nil
+}
+
+func (mp *MP) createQRCode(action string, scene qrScene, expireSeconds int, timeout int) (qrcode *QRCode, err error) {
+	temporary := action == QRScene || action == QRStrScene
+	if temporary && (expireSeconds <= 0 || expireSeconds > MaxQRExpireSeconds) {
+		return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("expireSeconds: %d, expect 1 to %d", expireSeconds, MaxQRExpireSeconds)}
+	}
+
+	body := struct {
+		ExpireSeconds int    `json:"expire_seconds,omitempty"`
+		ActionName    string `json:"action_name"`
+		ActionInfo    struct {
+			Scene qrScene `json:"scene"`
+		} `json:"action_info"`
+	}{ExpireSeconds: expireSeconds, ActionName: action}
+	body.ActionInfo.Scene = scene
+
+	qrcode = new(QRCode)
+	err = mp.postJSON(qrcodeCreatePath, body, qrcode, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return qrcode, nil
+}
+
+// QRCodeURL of qrcode image by ticket, no access token required.
+// https://mp.weixin.qq.com/cgi-bin/showqrcode?ticket=TICKET
+func QRCodeURL(ticket string) string {
+	return qrcodeShowPath + "?ticket=" + url.QueryEscape(ticket)
+}
+
+// ShowQRCode copies qrcode image of ticket into w.
+// https://mp.weixin.qq.com/cgi-bin/showqrcode?ticket=TICKET
+func ShowQRCode(ticket string, w io.Writer, timeout int) error {
+	if len(ticket) <= 0 {
+		return wx.ParameterError{InvalidParameter: "ticket"}
+	}
+
+	req := wx.HttpClient{
+		Path:    qrcodeShowPath,
+		Timeout: timeout,
+		Parameters: []wx.QueryParameter{
+			{Key: "ticket", Value: url.QueryEscape(ticket)},
+		},
+	}
+	binary, err := req.Download("GET", nil, w, nil)
+	if err != nil {
+		return err
+	}
+	if !binary {
+		return wx.WeshinError{Code: "qrcode", Detail: "no image of ticket " + ticket}
+	}
+	return nil
+}
+
+// GenShortKey for long data, expires in seconds no more than 30 days.
+// https://api.weixin.qq.com/cgi-bin/shorten/gen?access_token=ACCESS_TOKEN
+func (mp *MP) GenShortKey(longData string, expireSeconds int, timeout int) (shortKey string, err error) {
+	if len(longData) == 0 || len(longData) > maxShortenDataLen {
+		return "", wx.ParameterError{InvalidParameter: fmt.Sprintf("longData: %d bytes, expect 1 to %d", len(longData), maxShortenDataLen)}
+	}
+	if expireSeconds < 0 || expireSeconds > MaxShortenExpireSecond {
+		return "", wx.ParameterError{InvalidParameter: fmt.Sprintf("expireSeconds: %d, expect no more than %d", expireSeconds, MaxShortenExpireSecond)}
+	}
+
+	body := struct {
+		LongData      string `json:"long_data"`
+		ExpireSeconds int    `json:"expire_seconds,omitempty"`
+	}{longData, expireSeconds}
+
+	var resp struct {
+		ShortKey string `json:"short_key"`
+	}
+	err = mp.postJSON(shortenGenPath, body, &resp, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.ShortKey, nil
+}
+
+// ShortData of short key.
+type ShortData struct {
+	LongData      string `json:"long_data"`
+	CreateTime    int64  `json:"create_time"`
+	ExpireSeconds int64  `json:"expire_seconds"` // remaining
+}
+
+// FetchShortKey returns long data of short key.
+// https://api.weixin.qq.com/cgi-bin/shorten/fetch?access_token=ACCESS_TOKEN
+func (mp *MP) FetchShortKey(shortKey string, timeout int) (data *ShortData, err error) {
+	if len(shortKey) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "shortKey"}
+	}
+
+	body := struct {
+		ShortKey string `json:"short_key"`
+	}{shortKey}
+
+	data = new(ShortData)
+	err = mp.postJSON(shortenFetchPath, body, data, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
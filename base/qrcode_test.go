@@ -0,0 +1,74 @@
+package base
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQRCodeValidation(t *testing.T) {
+	mp := new(MP)
+	for i, c := range []struct {
+		create  func() (*QRCode, error)
+		invalid string
+	}{
+		{func() (*QRCode, error) { return mp.CreateTempQRCode(0, 60, 0) }, "sceneID"},
+		{func() (*QRCode, error) { return mp.CreateTempQRCode(1, 0, 0) }, "expireSeconds"},
+		{func() (*QRCode, error) { return mp.CreateTempQRCode(1, MaxQRExpireSeconds+1, 0) }, "expireSeconds"},
+		{func() (*QRCode, error) { return mp.CreateTempStrQRCode(strings.Repeat("s", 65), 60, 0) }, "scene"},
+		{func() (*QRCode, error) { return mp.CreateQRCode(MaxQRLimitSceneID+1, 0) }, "sceneID"},
+		{func() (*QRCode, error) { return mp.CreateStrQRCode("", 0) }, "scene"},
+	} {
+		_, err := c.create()
+		if err == nil || !strings.Contains(err.Error(), c.invalid) {
+			t.Errorf("case %d: expect invalid %s, got %v", i, c.invalid, err)
+		}
+	}
+
+	u := QRCodeURL("gQH47joAAAAAAAAAASxodHRwOi8vd2VpeGluLnFxLmNvbS9xL2taZ2Z3TVRtNzJXV1Brb3ZhYmJJAAIEZ23sUwMEmm3sUw==")
+	if !strings.HasSuffix(u, "?ticket=gQH47joAAAAAAAAAASxodHRwOi8vd2VpeGluLnFxLmNvbS9xL2taZ2Z3TVRtNzJXV1Brb3ZhYmJJAAIEZ23sUwMEmm3sUw%3D%3D") {
+		t.Error("url: ", u)
+	}
+}
+
+func TestCreateQRCode(t *testing.T) {
+	api, stop := serveFakeAPI(func(r *apiRequest) string {
+		return `{"ticket":"gQH47joAAAAAAAAAASxodHRwOi8vd2VpeGluLnFxLmNvbS9xL2taZ2Z3TVRtNzJXV1Brb3ZhYmJJAAIEZ23sUwMEmm3sUw==","expire_seconds":60,"url":"http://weixin.qq.com/q/kZgfwMTm72WWPkovabbI"}`
+	})
+	defer stop()
+
+	mp := newTestMP()
+	for _, c := range []struct {
+		create func() (*QRCode, error)
+		body   string
+	}{
+		{
+			func() (*QRCode, error) { return mp.CreateTempQRCode(123, 604800, 0) },
+			`{"expire_seconds":604800,"action_name":"QR_SCENE","action_info":{"scene":{"scene_id":123}}}`,
+		},
+		{
+			func() (*QRCode, error) { return mp.CreateTempStrQRCode("poster", 60, 0) },
+			`{"expire_seconds":60,"action_name":"QR_STR_SCENE","action_info":{"scene":{"scene_str":"poster"}}}`,
+		},
+		{
+			func() (*QRCode, error) { return mp.CreateQRCode(MaxQRLimitSceneID, 0) },
+			`{"action_name":"QR_LIMIT_SCENE","action_info":{"scene":{"scene_id":100000}}}`,
+		},
+		{
+			func() (*QRCode, error) { return mp.CreateStrQRCode("staff_42", 0) },
+			`{"action_name":"QR_LIMIT_STR_SCENE","action_info":{"scene":{"scene_str":"staff_42"}}}`,
+		},
+	} {
+		api.requests = nil
+		qrcode, err := c.create()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if qrcode.ExpireSeconds != 60 || qrcode.URL != "http://weixin.qq.com/q/kZgfwMTm72WWPkovabbI" {
+			t.Errorf("unexpected qrcode: %+v", qrcode)
+		}
+		requests := api.served(qrcodeCreatePath)
+		if len(requests) != 1 || string(requests[0].Body) != c.body {
+			t.Errorf("%d requests, expect 1 of %s", len(requests), c.body)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 消息管理/订阅通知接口
+ * https://mp.weixin.qq.com/wiki/ 消息管理/一次性订阅消息
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	subscribeCategoryPath       = "https://api.weixin.qq.com/wxaapi/newtmpl/getcategory"
+	subscribePubTitlesPath      = "https://api.weixin.qq.com/wxaapi/newtmpl/getpubtemplatetitles"
+	subscribePubKeywordsPath    = "https://api.weixin.qq.com/wxaapi/newtmpl/getpubtemplatekeywords"
+	subscribeAddTemplatePath    = "https://api.weixin.qq.com/wxaapi/newtmpl/addtemplate"
+	subscribeDeleteTemplatePath = "https://api.weixin.qq.com/wxaapi/newtmpl/deltemplate"
+	subscribeTemplatesPath      = "https://api.weixin.qq.com/wxaapi/newtmpl/gettemplate"
+	subscribeBizSendPath        = "https://api.weixin.qq.com/cgi-bin/message/subscribe/bizsend"
+	subscribeOnceAuthPath       = "https://mp.weixin.qq.com/mp/subscribemsg"
+	subscribeOnceSendPath       = "https://api.weixin.qq.com/cgi-bin/message/template/subscribe"
+)
+
+// limits of subscribe messages
+const (
+	MaxPubTemplateTitles  = 30
+	maxSubscribeKeywords  = 5
+	maxSubscribeSceneDesc = 15
+	MaxSubscribeOnceScene = 10000
+	maxSubscribeReserved  = 128
+	maxSubscribeOnceTitle = 15 // runes
+	maxSubscribeOnceValue = 200
+)
+
+// type of subscribe template
+const (
+	SubscribeTemplateOnce = 2 // one time subscription
+	SubscribeTemplateLong = 3 // long term subscription
+)
+
+// max runes of value by keyword type, the type is keyword name without trailing digits.
+var subscribeValueLimits = map[string]int{
+	"thing":            20,
+	"number":           32,
+	"letter":           32,
+	"symbol":           5,
+	"character_string": 32,
+	"phone_number":     17,
+	"car_number":       8,
+	"name":             10,
+	"phrase":           5,
+}
+
+// SubscribeCategory of official account.
+type SubscribeCategory struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+}
+
+// PubTemplateTitle in template library.
+type PubTemplateTitle struct {
+	TID        int32  `json:"tid"`
+	Title      string `json:"title"`
+	Type       int32  `json:"type"` // SubscribeTemplateOnce or SubscribeTemplateLong
+	CategoryID string `json:"categoryId"`
+}
+
+// PubTemplateTitles of categories.
+type PubTemplateTitles struct {
+	Count int32              `json:"count"`
+	Data  []PubTemplateTitle `json:"data"`
+}
+
+// PubTemplateKeyword of template in template library.
+type PubTemplateKeyword struct {
+	KID     int32  `json:"kid"`
+	Name    string `json:"name"`
+	Example string `json:"example"`
+	Rule    string `json:"rule"` // keyword type, such as thing and number
+}
+
+// SubscribeTemplate added to official account.
+type SubscribeTemplate struct {
+	PriTmplID string `json:"priTmplId"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	Example   string `json:"example"`
+	Type      int32  `json:"type"` // SubscribeTemplateOnce or SubscribeTemplateLong
+}
+
+// Keywords in template content, such as thing1 and time2.
+func (t *SubscribeTemplate) Keywords() []string {
+	return (&Template{Content: t.Content}).Keywords()
+}
+
+// SubscribeMessage to send by bizsend, built by NewSubscribeMessage.
+type SubscribeMessage struct {
+	ToUser      string                   `json:"touser"`
+	TemplateID  string                   `json:"template_id"`
+	Page        string                   `json:"page,omitempty"`
+	MiniProgram *TemplateMiniProgram     `json:"miniprogram,omitempty"`
+	Data        map[string]TemplateValue `json:"data"`
+}
+
+// NewSubscribeMessage to user with private template.
+func NewSubscribeMessage(toUser, templateID string) *SubscribeMessage {
+	return &SubscribeMessage{
+		ToUser:     toUser,
+		TemplateID: templateID,
+		Data:       make(map[string]TemplateValue),
+	}
+}
+
+// Set value of keyword.
+func (m *SubscribeMessage) Set(keyword, value string) *SubscribeMessage {
+	if m.Data == nil {
+		m.Data = make(map[string]TemplateValue)
+	}
+	m.Data[keyword] = TemplateValue{Value: value}
+	return m
+}
+
+// JumpPage of web when message clicked.
+func (m *SubscribeMessage) JumpPage(page string) *SubscribeMessage {
+	m.Page = page
+	return m
+}
+
+// JumpMiniProgram when message clicked.
+func (m *SubscribeMessage) JumpMiniProgram(appID, pagePath string) *SubscribeMessage {
+	m.MiniProgram = &TemplateMiniProgram{appID, pagePath}
+	return m
+}
+
+// Validate message against keywords of template, with length limits of keyword types.
+func (m *SubscribeMessage) Validate(t *SubscribeTemplate) error {
+	if m.TemplateID != t.PriTmplID {
+		return wx.ParameterError{InvalidParameter: "templateID: mismatched " + m.TemplateID}
+	}
+
+	return validateKeywords(t.Keywords(), m.Data, func(k string, v TemplateValue) error {
+		limit, ok := subscribeValueLimits[strings.TrimRight(k, "0123456789")]
+		if ok && utf8.RuneCountInString(v.Value) > limit {
+			return wx.ParameterError{InvalidParameter: fmt.Sprintf("data.%s.value: longer than %d characters", k, limit)}
+		}
+		return nil
+	})
+}
+
+// GetSubscribeCategories returns categories of official account.
+// https://api.weixin.qq.com/wxaapi/newtmpl/getcategory?access_token=ACCESS_TOKEN
+func (mp *MP) GetSubscribeCategories(timeout int) (categories []SubscribeCategory, err error) {
+	var resp struct {
+		Data []SubscribeCategory `json:"data"`
+	}
+	err = mp.getJSON(subscribeCategoryPath, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// GetPubTemplateTitles returns titles of templates in categories, no more than 30 at a time.
+// https://api.weixin.qq.com/wxaapi/newtmpl/getpubtemplatetitles?access_token=ACCESS_TOKEN&ids=IDS&start=START&limit=LIMIT
+func (mp *MP) GetPubTemplateTitles(categoryIDs []int32, start, limit int, timeout int) (titles *PubTemplateTitles, err error) {
+	if len(categoryIDs) == 0 {
+		return nil, wx.ParameterError{InvalidParameter: "categoryIDs"}
+	}
+	if limit <= 0 || limit > MaxPubTemplateTitles {
+		return nil, wx.ParameterError{InvalidParameter: "limit"}
+	}
+
+	ids := make([]string, len(categoryIDs))
+	for i, id := range categoryIDs {
+		ids[i] = strconv.Itoa(int(id))
+	}
+
+	titles = new(PubTemplateTitles)
+	err = mp.getJSON(subscribePubTitlesPath, titles, timeout,
+		wx.QueryParameter{Key: "ids", Value: strings.Join(ids, ",")},
+		wx.QueryParameter{Key: "start", Value: strconv.Itoa(start)},
+		wx.QueryParameter{Key: "limit", Value: strconv.Itoa(limit)},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return titles, nil
+}
+
+// GetPubTemplateKeywords returns keywords of template in template library.
+// https://api.weixin.qq.com/wxaapi/newtmpl/getpubtemplatekeywords?access_token=ACCESS_TOKEN&tid=TID
+func (mp *MP) GetPubTemplateKeywords(tid int32, timeout int) (keywords []PubTemplateKeyword, err error) {
+	var resp struct {
+		Data []PubTemplateKeyword `json:"data"`
+	}
+	err = mp.getJSON(subscribePubKeywordsPath, &resp, timeout,
+		wx.QueryParameter{Key: "tid", Value: strconv.Itoa(int(tid))},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// AddSubscribeTemplate from template library with keywords in order, returns private template id.
+// https://api.weixin.qq.com/wxaapi/newtmpl/addtemplate?access_token=ACCESS_TOKEN
+func (mp *MP) AddSubscribeTemplate(tid int32, kids []int32, sceneDesc string, timeout int) (templateID string, err error) {
+	if len(kids) < 2 || len(kids) > maxSubscribeKeywords {
+		return "", wx.ParameterError{InvalidParameter: fmt.Sprintf("kidList: %d keywords, expect 2 to %d", len(kids), maxSubscribeKeywords)}
+	}
+	if utf8.RuneCountInString(sceneDesc) > maxSubscribeSceneDesc {
+		return "", wx.ParameterError{InvalidParameter: "sceneDesc"}
+	}
+
+	body := struct {
+		TID       int32   `json:"tid"`
+		KIDList   []int32 `json:"kidList"`
+		SceneDesc string  `json:"sceneDesc,omitempty"`
+	}{tid, kids, sceneDesc}
+
+	var resp struct {
+		PriTmplID string `json:"priTmplId"`
+	}
+	err = mp.postJSON(subscribeAddTemplatePath, body, &resp, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.PriTmplID, nil
+}
+
+// DeleteSubscribeTemplate by private template id.
+// https://api.weixin.qq.com/wxaapi/newtmpl/deltemplate?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteSubscribeTemplate(templateID string, timeout int) error {
+	if len(templateID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "templateID"}
+	}
+
+	body := struct {
+		PriTmplID string `json:"priTmplId"`
+	}{templateID}
+
+	return mp.postJSON(subscribeDeleteTemplatePath, body, nil, timeout)
+}
+
+// GetSubscribeTemplates returns private templates added.
+// https://api.weixin.qq.com/wxaapi/newtmpl/gettemplate?access_token=ACCESS_TOKEN
+func (mp *MP) GetSubscribeTemplates(timeout int) (templates []SubscribeTemplate, err error) {
+	var resp struct {
+		Data []SubscribeTemplate `json:"data"`
+	}
+	err = mp.getJSON(subscribeTemplatesPath, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// SendSubscribeMessage to user subscribed to template.
+// https://api.weixin.qq.com/cgi-bin/message/subscribe/bizsend?access_token=ACCESS_TOKEN
+func (mp *MP) SendSubscribeMessage(msg *SubscribeMessage, timeout int) error {
+	if len(msg.ToUser) <= 0 {
+		return wx.ParameterError{InvalidParameter: "toUser"}
+	}
+	if len(msg.TemplateID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "templateID"}
+	}
+
+	return mp.postJSON(subscribeBizSendPath, msg, nil, timeout)
+}
+
+// SubscribeOnceURL compose page url for user to authorize one time subscribe message,
+// user is redirected to redirectURL with openid, template_id, action, scene and reserved in query.
+// https://mp.weixin.qq.com/mp/subscribemsg?action=get_confirm&appid=APPID&scene=SCENE&template_id=TEMPLATE_ID&redirect_url=REDIRECT_URL&reserved=RESERVED#wechat_redirect
+func (mp *MP) SubscribeOnceURL(scene int, templateID, redirectURL, reserved string) (string, error) {
+	if scene < 0 || scene > MaxSubscribeOnceScene {
+		return "", wx.ParameterError{InvalidParameter: "scene"}
+	}
+	if len(templateID) <= 0 {
+		return "", wx.ParameterError{InvalidParameter: "templateID"}
+	}
+	if len(redirectURL) <= 0 {
+		return "", wx.ParameterError{InvalidParameter: "redirectURL"}
+	}
+	if len(reserved) > maxSubscribeReserved {
+		return "", wx.ParameterError{InvalidParameter: "reserved"}
+	}
+
+	u := bytes.NewBufferString(subscribeOnceAuthPath)
+	u.WriteString("?action=get_confirm")
+	u.WriteString("&appid=")
+	u.WriteString(mp.AppID)
+	u.WriteString("&scene=")
+	u.WriteString(strconv.Itoa(scene))
+	u.WriteString("&template_id=")
+	u.WriteString(url.QueryEscape(templateID))
+	u.WriteString("&redirect_url=")
+	u.WriteString(url.QueryEscape(redirectURL))
+	if len(reserved) > 0 {
+		u.WriteString("&reserved=")
+		u.WriteString(url.QueryEscape(reserved))
+	}
+	u.WriteString("#wechat_redirect")
+
+	return u.String(), nil
+}
+
+// SubscribeOnceAuth is the result of user authorization in query of redirect url.
+type SubscribeOnceAuth struct {
+	OpenID     string
+	TemplateID string
+	Action     string // confirm or cancel
+	Scene      int
+	Reserved   string
+}
+
+// Confirmed by user.
+func (a *SubscribeOnceAuth) Confirmed() bool {
+	return a.Action == "confirm"
+}
+
+// ParseSubscribeOnceAuth parses query of redirect url after user authorization.
+func ParseSubscribeOnceAuth(query url.Values) (*SubscribeOnceAuth, error) {
+	a := &SubscribeOnceAuth{
+		OpenID:     query.Get("openid"),
+		TemplateID: query.Get("template_id"),
+		Action:     query.Get("action"),
+		Reserved:   query.Get("reserved"),
+	}
+	if len(a.OpenID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "openid"}
+	}
+	scene, err := strconv.Atoi(query.Get("scene"))
+	if err != nil {
+		return nil, wx.ParameterError{InvalidParameter: "scene"}
+	}
+	a.Scene = scene
+	return a, nil
+}
+
+// SubscribeOnceMessage to user authorized by SubscribeOnceURL, Data holds only "content".
+type SubscribeOnceMessage struct {
+	ToUser      string                   `json:"touser"`
+	TemplateID  string                   `json:"template_id"`
+	URL         string                   `json:"url,omitempty"`
+	MiniProgram *TemplateMiniProgram     `json:"miniprogram,omitempty"`
+	Scene       string                   `json:"scene"`
+	Title       string                   `json:"title"`
+	Data        map[string]TemplateValue `json:"data"`
+}
+
+// NewSubscribeOnceMessage to user authorized with scene.
+func NewSubscribeOnceMessage(auth *SubscribeOnceAuth, title, content, color string) *SubscribeOnceMessage {
+	return &SubscribeOnceMessage{
+		ToUser:     auth.OpenID,
+		TemplateID: auth.TemplateID,
+		Scene:      strconv.Itoa(auth.Scene),
+		Title:      title,
+		Data: map[string]TemplateValue{
+			"content": {content, color},
+		},
+	}
+}
+
+// SendSubscribeOnceMessage to user authorized, once for each authorization.
+// https://api.weixin.qq.com/cgi-bin/message/template/subscribe?access_token=ACCESS_TOKEN
+func (mp *MP) SendSubscribeOnceMessage(msg *SubscribeOnceMessage, timeout int) error {
+	if len(msg.ToUser) <= 0 {
+		return wx.ParameterError{InvalidParameter: "toUser"}
+	}
+	if len(msg.TemplateID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "templateID"}
+	}
+	if utf8.RuneCountInString(msg.Title) > maxSubscribeOnceTitle {
+		return wx.ParameterError{InvalidParameter: "title"}
+	}
+	if utf8.RuneCountInString(msg.Data["content"].Value) > maxSubscribeOnceValue {
+		return wx.ParameterError{InvalidParameter: "data.content"}
+	}
+
+	return mp.postJSON(subscribeOnceSendPath, msg, nil, timeout)
+}
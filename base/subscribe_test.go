@@ -0,0 +1,59 @@
+package base
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSubscribeMessage(t *testing.T) {
+	tpl := &SubscribeTemplate{
+		PriTmplID: "9Aw5ZV1j9xdWTFEkqCpZ7mIBbSC34khK55OtzUPl0rU",
+		Content:   "会议时间:{{date2.DATA}}\n会议地点:{{thing1.DATA}}\n",
+		Type:      SubscribeTemplateLong,
+	}
+	msg := NewSubscribeMessage("OPENID", tpl.PriTmplID).
+		Set("date2", "2019年10月1日 15:01").
+		Set("thing1", "广州市天河区").
+		JumpPage("https://mp.weixin.qq.com")
+	if err := msg.Validate(tpl); err != nil {
+		t.Error("valid message: ", err)
+	}
+
+	msg.Set("thing1", strings.Repeat("长", 21))
+	if err := msg.Validate(tpl); err == nil || !strings.Contains(err.Error(), "data.thing1.value") {
+		t.Error("expect thing1 too long, got ", err)
+	}
+	delete(msg.Data, "thing1")
+	if err := msg.Validate(tpl); err == nil || !strings.Contains(err.Error(), "missing keywords thing1") {
+		t.Error("expect thing1 missing, got ", err)
+	}
+}
+
+func TestSubscribeOnce(t *testing.T) {
+	mp := &MP{AppID: "wxaba38c7f163da69b"}
+	u, err := mp.SubscribeOnceURL(1000, "TEMPLATE_ID", "http://example.com/sub?a=1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "https://mp.weixin.qq.com/mp/subscribemsg?action=get_confirm&appid=wxaba38c7f163da69b&scene=1000&template_id=TEMPLATE_ID&redirect_url=http%3A%2F%2Fexample.com%2Fsub%3Fa%3D1&reserved=test#wechat_redirect"
+	if u != expect {
+		t.Errorf("url: %s, expect %s", u, expect)
+	}
+	if _, err := mp.SubscribeOnceURL(10001, "TEMPLATE_ID", "http://example.com", ""); err == nil {
+		t.Error("expect invalid scene")
+	}
+
+	query, _ := url.ParseQuery("openid=OPENID&template_id=TEMPLATE_ID&action=confirm&scene=1000&reserved=test")
+	auth, err := ParseSubscribeOnceAuth(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.Confirmed() || auth.Scene != 1000 || auth.Reserved != "test" {
+		t.Errorf("auth: %+v", auth)
+	}
+	msg := NewSubscribeOnceMessage(auth, "标题", "内容", "#000000")
+	if msg.ToUser != "OPENID" || msg.Scene != "1000" || msg.Data["content"].Color != "#000000" {
+		t.Errorf("message: %+v", msg)
+	}
+}
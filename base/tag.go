@@ -0,0 +1,167 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 用户管理/用户标签管理
+ */
+
+import (
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	tagCreatePath         = "https://api.weixin.qq.com/cgi-bin/tags/create"
+	tagGetPath            = "https://api.weixin.qq.com/cgi-bin/tags/get"
+	tagUpdatePath         = "https://api.weixin.qq.com/cgi-bin/tags/update"
+	tagDeletePath         = "https://api.weixin.qq.com/cgi-bin/tags/delete"
+	tagBatchTaggingPath   = "https://api.weixin.qq.com/cgi-bin/tags/members/batchtagging"
+	tagBatchUntaggingPath = "https://api.weixin.qq.com/cgi-bin/tags/members/batchuntagging"
+	tagGetIDListPath      = "https://api.weixin.qq.com/cgi-bin/tags/getidlist"
+	tagUserGetPath        = "https://api.weixin.qq.com/cgi-bin/user/tag/get"
+)
+
+const (
+	maxTagNameLen   = 30 // bytes
+	maxTaggingUsers = 50
+)
+
+// Tag of users
+type Tag struct {
+	ID    int32  `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Count int64  `json:"count,omitempty"` // users with tag
+}
+
+// CreateTag with name, returns tag created with id.
+// https://api.weixin.qq.com/cgi-bin/tags/create?access_token=ACCESS_TOKEN
+func (mp *MP) CreateTag(name string, timeout int) (tag *Tag, err error) {
+	if len(name) <= 0 || len(name) > maxTagNameLen {
+		return nil, wx.ParameterError{InvalidParameter: "name"}
+	}
+
+	body := struct {
+		Tag Tag `json:"tag"`
+	}{Tag{Name: name}}
+
+	var resp struct {
+		Tag Tag `json:"tag"`
+	}
+	err = mp.postJSON(tagCreatePath, body, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Tag, nil
+}
+
+// GetTags returns all tags created.
+// https://api.weixin.qq.com/cgi-bin/tags/get?access_token=ACCESS_TOKEN
+func (mp *MP) GetTags(timeout int) (tags []Tag, err error) {
+	var resp struct {
+		Tags []Tag `json:"tags"`
+	}
+	err = mp.getJSON(tagGetPath, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+// UpdateTag renames tag.
+// https://api.weixin.qq.com/cgi-bin/tags/update?access_token=ACCESS_TOKEN
+func (mp *MP) UpdateTag(tagID int32, name string, timeout int) error {
+	if len(name) <= 0 || len(name) > maxTagNameLen {
+		return wx.ParameterError{InvalidParameter: "name"}
+	}
+
+	body := struct {
+		Tag Tag `json:"tag"`
+	}{Tag{ID: tagID, Name: name}}
+
+	return mp.postJSON(tagUpdatePath, body, nil, timeout)
+}
+
+// DeleteTag, and untag all users with it.
+// https://api.weixin.qq.com/cgi-bin/tags/delete?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteTag(tagID int32, timeout int) error {
+	body := struct {
+		Tag Tag `json:"tag"`
+	}{Tag{ID: tagID}}
+
+	return mp.postJSON(tagDeletePath, body, nil, timeout)
+}
+
+// TagUsers tags users in chunks of 50 openids,
+// users in chunks before error returned are tagged.
+// https://api.weixin.qq.com/cgi-bin/tags/members/batchtagging?access_token=ACCESS_TOKEN
+func (mp *MP) TagUsers(tagID int32, openIDs []string, timeout int) error {
+	return mp.batchTagging(tagBatchTaggingPath, tagID, openIDs, timeout)
+}
+
+// UntagUsers untags users in chunks of 50 openids,
+// users in chunks before error returned are untagged.
+// https://api.weixin.qq.com/cgi-bin/tags/members/batchuntagging?access_token=ACCESS_TOKEN
+func (mp *MP) UntagUsers(tagID int32, openIDs []string, timeout int) error {
+	return mp.batchTagging(tagBatchUntaggingPath, tagID, openIDs, timeout)
+}
+
+func (mp *MP) batchTagging(path string, tagID int32, openIDs []string, timeout int) error {
+	if len(openIDs) <= 0 {
+		return wx.ParameterError{InvalidParameter: "openIDs"}
+	}
+
+	for _, c := range chunk(openIDs, maxTaggingUsers) {
+		body := struct {
+			OpenIDList []string `json:"openid_list"`
+			TagID      int32    `json:"tagid"`
+		}{c, tagID}
+
+		err := mp.postJSON(path, body, nil, timeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserTags returns tag ids of user.
+// https://api.weixin.qq.com/cgi-bin/tags/getidlist?access_token=ACCESS_TOKEN
+func (mp *MP) GetUserTags(openID string, timeout int) (tagIDs []int32, err error) {
+	if len(openID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "openID"}
+	}
+
+	body := struct {
+		OpenID string `json:"openid"`
+	}{openID}
+
+	var resp struct {
+		TagIDList []int32 `json:"tagid_list"`
+	}
+	err = mp.postJSON(tagGetIDListPath, body, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.TagIDList, nil
+}
+
+// GetTagUsers returns a page of at most 10000 users with tag, after nextOpenID.
+// https://api.weixin.qq.com/cgi-bin/user/tag/get?access_token=ACCESS_TOKEN
+func (mp *MP) GetTagUsers(tagID int32, nextOpenID string, timeout int) (list *OpenIDList, err error) {
+	body := struct {
+		TagID      int32  `json:"tagid"`
+		NextOpenID string `json:"next_openid"`
+	}{tagID, nextOpenID}
+
+	list = new(OpenIDList)
+	err = mp.postJSON(tagUserGetPath, body, list, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// TagUserIterator iterates all users with tag.
+func (mp *MP) TagUserIterator(tagID int32, timeout int) *OpenIDIterator {
+	return NewOpenIDIterator("", func(nextOpenID string) (*OpenIDList, error) {
+		return mp.GetTagUsers(tagID, nextOpenID, timeout)
+	})
+}
@@ -0,0 +1,80 @@
+package base
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBatchTagging(t *testing.T) {
+	for _, c := range []struct {
+		users  int
+		chunks []int
+		ok     bool
+	}{
+		{0, nil, false},
+		{50, []int{50}, true},
+		{120, []int{50, 50, 20}, true},
+	} {
+		for _, path := range []string{tagBatchTaggingPath, tagBatchUntaggingPath} {
+			api, stop := serveFakeAPI(nil)
+			mp := newTestMP()
+			var err error
+			if path == tagBatchTaggingPath {
+				err = mp.TagUsers(100, openIDs(c.users), 0)
+			} else {
+				err = mp.UntagUsers(100, openIDs(c.users), 0)
+			}
+			stop()
+
+			if (err == nil) != c.ok {
+				t.Errorf("%d users: error %v", c.users, err)
+			}
+			requests := api.served(path)
+			if len(requests) != len(c.chunks) {
+				t.Errorf("%d users: %d requests to %s", c.users, len(requests), path)
+				continue
+			}
+			for i, r := range requests {
+				var body struct {
+					OpenIDList []string `json:"openid_list"`
+					TagID      int32    `json:"tagid"`
+				}
+				json.Unmarshal(r.Body, &body)
+				if len(body.OpenIDList) != c.chunks[i] || body.TagID != 100 {
+					t.Errorf("%d users: chunk %d of %d users, tag %d", c.users, i, len(body.OpenIDList), body.TagID)
+				}
+			}
+		}
+	}
+}
+
+func TestUpdateTag(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		ok   bool
+	}{
+		{"星标组", true},
+		{strings.Repeat("t", maxTagNameLen), true},
+		{strings.Repeat("t", maxTagNameLen+1), false},
+		{"", false},
+	} {
+		api, stop := serveFakeAPI(nil)
+		err := newTestMP().UpdateTag(100, c.name, 0)
+		stop()
+
+		if (err == nil) != c.ok {
+			t.Errorf("%q: error %v", c.name, err)
+		}
+		requests := api.served(tagUpdatePath)
+		if !c.ok {
+			if len(requests) != 0 {
+				t.Errorf("%q: invalid name sent", c.name)
+			}
+			continue
+		}
+		if len(requests) != 1 || string(requests[0].Body) != `{"tag":{"id":100,"name":"`+c.name+`"}}` {
+			t.Errorf("%q: %d requests", c.name, len(requests))
+		}
+	}
+}
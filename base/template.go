@@ -0,0 +1,260 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 消息管理/模板消息接口
+ */
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	templateSetIndustryPath = "https://api.weixin.qq.com/cgi-bin/template/api_set_industry"
+	templateGetIndustryPath = "https://api.weixin.qq.com/cgi-bin/template/get_industry"
+	templateAddPath         = "https://api.weixin.qq.com/cgi-bin/template/api_add_template"
+	templateListPath        = "https://api.weixin.qq.com/cgi-bin/template/get_all_private_template"
+	templateDeletePath      = "https://api.weixin.qq.com/cgi-bin/template/del_private_template"
+	templateSendPath        = "https://api.weixin.qq.com/cgi-bin/message/template/send"
+)
+
+var (
+	templateKeywordPattern = regexp.MustCompile(`\{\{\s*(\w+)\.DATA\s*\}\}`)
+	templateColorPattern   = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// Industry of official account for templates.
+type Industry struct {
+	FirstClass  string `json:"first_class"`
+	SecondClass string `json:"second_class"`
+}
+
+// IndustryInfo of official account.
+type IndustryInfo struct {
+	PrimaryIndustry   Industry `json:"primary_industry"`
+	SecondaryIndustry Industry `json:"secondary_industry"`
+}
+
+// Template added to official account.
+type Template struct {
+	TemplateID      string `json:"template_id"`
+	Title           string `json:"title"`
+	PrimaryIndustry string `json:"primary_industry"`
+	DeputyIndustry  string `json:"deputy_industry"`
+	Content         string `json:"content"`
+	Example         string `json:"example"`
+}
+
+// Keywords in template content, such as first, keyword1 and remark.
+func (t *Template) Keywords() []string {
+	var keywords []string
+	for _, m := range templateKeywordPattern.FindAllStringSubmatch(t.Content, -1) {
+		keywords = append(keywords, m[1])
+	}
+	return keywords
+}
+
+// TemplateValue of keyword, Color in #RRGGBB.
+type TemplateValue struct {
+	Value string `json:"value"`
+	Color string `json:"color,omitempty"`
+}
+
+// TemplateMiniProgram to jump to, in place of URL if supported by client.
+type TemplateMiniProgram struct {
+	AppID    string `json:"appid"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// TemplateMessage to send, built by NewTemplateMessage.
+type TemplateMessage struct {
+	ToUser      string                   `json:"touser"`
+	TemplateID  string                   `json:"template_id"`
+	URL         string                   `json:"url,omitempty"`
+	MiniProgram *TemplateMiniProgram     `json:"miniprogram,omitempty"`
+	ClientMsgID string                   `json:"client_msg_id,omitempty"` // to avoid sending again in 24 hours
+	Data        map[string]TemplateValue `json:"data"`
+}
+
+// NewTemplateMessage to user with template.
+//
+//	msg := NewTemplateMessage(openID, templateID).
+//		Set("first", "order paid").
+//		SetColor("keyword1", "¥9.90", "#FF0000").
+//		JumpURL(orderURL)
+func NewTemplateMessage(toUser, templateID string) *TemplateMessage {
+	return &TemplateMessage{
+		ToUser:     toUser,
+		TemplateID: templateID,
+		Data:       make(map[string]TemplateValue),
+	}
+}
+
+// Set value of keyword.
+func (m *TemplateMessage) Set(keyword, value string) *TemplateMessage {
+	return m.SetColor(keyword, value, "")
+}
+
+// SetColor sets value of keyword in color.
+func (m *TemplateMessage) SetColor(keyword, value, color string) *TemplateMessage {
+	if m.Data == nil {
+		m.Data = make(map[string]TemplateValue)
+	}
+	m.Data[keyword] = TemplateValue{value, color}
+	return m
+}
+
+// JumpURL when message clicked.
+func (m *TemplateMessage) JumpURL(url string) *TemplateMessage {
+	m.URL = url
+	return m
+}
+
+// JumpMiniProgram when message clicked.
+func (m *TemplateMessage) JumpMiniProgram(appID, pagePath string) *TemplateMessage {
+	m.MiniProgram = &TemplateMiniProgram{appID, pagePath}
+	return m
+}
+
+// Validate message against keywords of template,
+// all keywords should be set and no more, with valid colors.
+func (m *TemplateMessage) Validate(t *Template) error {
+	if m.TemplateID != t.TemplateID {
+		return wx.ParameterError{InvalidParameter: "templateID: mismatched " + m.TemplateID}
+	}
+
+	return validateKeywords(t.Keywords(), m.Data, func(k string, v TemplateValue) error {
+		if len(v.Color) > 0 && !templateColorPattern.MatchString(v.Color) {
+			return wx.ParameterError{InvalidParameter: "data." + k + ".color: " + v.Color}
+		}
+		return nil
+	})
+}
+
+// validateKeywords checks data set for all keywords and no more,
+// check is called for values of keywords.
+func validateKeywords(keywords []string, data map[string]TemplateValue, check func(keyword string, v TemplateValue) error) error {
+	var missing, unknown []string
+	for _, k := range keywords {
+		if _, ok := data[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	for k, v := range data {
+		found := false
+		for _, keyword := range keywords {
+			if keyword == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, k)
+			continue
+		}
+		if err := check(k, v); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return wx.ParameterError{InvalidParameter: "data: missing keywords " + strings.Join(missing, ", ")}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return wx.ParameterError{InvalidParameter: "data: unknown keywords " + strings.Join(unknown, ", ")}
+	}
+	return nil
+}
+
+// SetIndustry of official account, modifiable once a month.
+// https://api.weixin.qq.com/cgi-bin/template/api_set_industry?access_token=ACCESS_TOKEN
+func (mp *MP) SetIndustry(primaryID, secondaryID string, timeout int) error {
+	body := struct {
+		IndustryID1 string `json:"industry_id1"`
+		IndustryID2 string `json:"industry_id2"`
+	}{primaryID, secondaryID}
+
+	return mp.postJSON(templateSetIndustryPath, body, nil, timeout)
+}
+
+// GetIndustry of official account.
+// https://api.weixin.qq.com/cgi-bin/template/get_industry?access_token=ACCESS_TOKEN
+func (mp *MP) GetIndustry(timeout int) (info *IndustryInfo, err error) {
+	info = new(IndustryInfo)
+	err = mp.getJSON(templateGetIndustryPath, info, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// AddTemplate from template library by short id, returns template id.
+// https://api.weixin.qq.com/cgi-bin/template/api_add_template?access_token=ACCESS_TOKEN
+func (mp *MP) AddTemplate(shortID string, timeout int) (templateID string, err error) {
+	if len(shortID) <= 0 {
+		return "", wx.ParameterError{InvalidParameter: "shortID"}
+	}
+
+	body := struct {
+		TemplateIDShort string `json:"template_id_short"`
+	}{shortID}
+
+	var resp struct {
+		TemplateID string `json:"template_id"`
+	}
+	err = mp.postJSON(templateAddPath, body, &resp, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.TemplateID, nil
+}
+
+// GetTemplates returns all templates added.
+// https://api.weixin.qq.com/cgi-bin/template/get_all_private_template?access_token=ACCESS_TOKEN
+func (mp *MP) GetTemplates(timeout int) (templates []Template, err error) {
+	var resp struct {
+		TemplateList []Template `json:"template_list"`
+	}
+	err = mp.getJSON(templateListPath, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.TemplateList, nil
+}
+
+// DeleteTemplate by template id.
+// https://api.weixin.qq.com/cgi-bin/template/del_private_template?access_token=ACCESS_TOKEN
+func (mp *MP) DeleteTemplate(templateID string, timeout int) error {
+	if len(templateID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "templateID"}
+	}
+
+	body := struct {
+		TemplateID string `json:"template_id"`
+	}{templateID}
+
+	return mp.postJSON(templateDeletePath, body, nil, timeout)
+}
+
+// SendTemplateMessage returns msgid, which is reported again in TEMPLATESENDJOBFINISH event.
+// https://api.weixin.qq.com/cgi-bin/message/template/send?access_token=ACCESS_TOKEN
+func (mp *MP) SendTemplateMessage(msg *TemplateMessage, timeout int) (msgID int64, err error) {
+	if len(msg.ToUser) <= 0 {
+		return 0, wx.ParameterError{InvalidParameter: "toUser"}
+	}
+	if len(msg.TemplateID) <= 0 {
+		return 0, wx.ParameterError{InvalidParameter: "templateID"}
+	}
+
+	var resp struct {
+		MsgID int64 `json:"msgid"`
+	}
+	err = mp.postJSON(templateSendPath, msg, &resp, timeout)
+	if err != nil {
+		return 0, err
+	}
+	return resp.MsgID, nil
+}
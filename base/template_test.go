@@ -0,0 +1,60 @@
+package base
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTemplateMessage(t *testing.T) {
+	tpl := &Template{
+		TemplateID: "iPk5sOIt5X_flOVKn5GrTFpncEYTojx6ddbt8WYoV5s",
+		Content:    "{{first.DATA}}\n商品名称：{{keyword1.DATA}}\n金额：{{ keyword2.DATA }}\n{{remark.DATA}}",
+	}
+	keywords := tpl.Keywords()
+	if strings.Join(keywords, ",") != "first,keyword1,keyword2,remark" {
+		t.Fatal("keywords: ", keywords)
+	}
+
+	msg := NewTemplateMessage("OPENID", tpl.TemplateID).
+		Set("first", "订单已支付").
+		Set("keyword1", "巧克力").
+		SetColor("keyword2", "39.8元", "#173177").
+		Set("remark", "").
+		JumpMiniProgram("xiaochengxuappid12345", "index?foo=bar")
+	if err := msg.Validate(tpl); err != nil {
+		t.Error("valid message: ", err)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"keyword2":{"value":"39.8元","color":"#173177"}`,
+		`"miniprogram":{"appid":"xiaochengxuappid12345","pagepath":"index?foo=bar"}`,
+	} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("marshaled %s, expect %s", data, s)
+		}
+	}
+	if strings.Contains(string(data), `"url"`) {
+		t.Errorf("marshaled %s, expect no url", data)
+	}
+
+	cases := []struct {
+		msg     *TemplateMessage
+		invalid string
+	}{
+		{NewTemplateMessage("OPENID", "other"), "templateID"},
+		{NewTemplateMessage("OPENID", tpl.TemplateID).Set("first", "x"), "missing keywords keyword1, keyword2, remark"},
+		{NewTemplateMessage("OPENID", tpl.TemplateID).Set("first", "").Set("keyword1", "").Set("keyword2", "").Set("remark", "").Set("keyword3", ""), "unknown keywords keyword3"},
+		{NewTemplateMessage("OPENID", tpl.TemplateID).SetColor("first", "x", "red"), "data.first.color"},
+	}
+	for i, c := range cases {
+		err := c.msg.Validate(tpl)
+		if err == nil || !strings.Contains(err.Error(), c.invalid) {
+			t.Errorf("case %d: expect invalid %s, got %v", i, c.invalid, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package base
+
+/**
+ * https://mp.weixin.qq.com/wiki/ 用户管理/获取用户列表
+ * https://mp.weixin.qq.com/wiki/ 用户管理/获取用户基本信息(UnionID机制)
+ * https://mp.weixin.qq.com/wiki/ 用户管理/设置用户备注名
+ */
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	followerListPath     = "https://api.weixin.qq.com/cgi-bin/user/get"
+	userinfoBatchGetPath = "https://api.weixin.qq.com/cgi-bin/user/info/batchget"
+	updateRemarkPath     = "https://api.weixin.qq.com/cgi-bin/user/info/updateremark"
+)
+
+const (
+	maxBatchGetUsers   = 100
+	defaultConcurrency = 4
+	maxRemarkLen       = 30 // characters
+)
+
+// GetFollowers returns a page of at most 10000 followers after nextOpenID,
+// from the beginning if nextOpenID is empty.
+// https://api.weixin.qq.com/cgi-bin/user/get?access_token=ACCESS_TOKEN&next_openid=NEXT_OPENID
+func (mp *MP) GetFollowers(nextOpenID string, timeout int) (list *OpenIDList, err error) {
+	list = new(OpenIDList)
+	err = mp.getJSON(followerListPath, list, timeout, wx.QueryParameter{Key: "next_openid", Value: nextOpenID})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// FollowerIterator iterates followers after nextOpenID.
+func (mp *MP) FollowerIterator(nextOpenID string, timeout int) *OpenIDIterator {
+	return NewOpenIDIterator(nextOpenID, func(nextOpenID string) (*OpenIDList, error) {
+		return mp.GetFollowers(nextOpenID, timeout)
+	})
+}
+
+// BatchGetUserInfo of at most 100 users in one request.
+// https://api.weixin.qq.com/cgi-bin/user/info/batchget?access_token=ACCESS_TOKEN
+func (mp *MP) BatchGetUserInfo(openIDs []string, lang string, timeout int) (infos []*wx.UserInfo, err error) {
+	if len(openIDs) <= 0 || len(openIDs) > maxBatchGetUsers {
+		return nil, wx.ParameterError{InvalidParameter: "openIDs"}
+	}
+	lang, err = checkLang(lang)
+	if err != nil {
+		return nil, err
+	}
+
+	type user struct {
+		OpenID string `json:"openid"`
+		Lang   string `json:"lang"`
+	}
+	body := struct {
+		UserList []user `json:"user_list"`
+	}{make([]user, 0, len(openIDs))}
+	for _, id := range openIDs {
+		body.UserList = append(body.UserList, user{id, lang})
+	}
+
+	var resp struct {
+		UserInfoList []*wx.UserInfo `json:"user_info_list"`
+	}
+	err = mp.postJSON(userinfoBatchGetPath, body, &resp, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return resp.UserInfoList, nil
+}
+
+// UserInfoHandler handles user info of a chunk of openids, err for the chunk failed.
+type UserInfoHandler func(infos []*wx.UserInfo, openIDs []string, err error)
+
+// FetchUserInfo fetches user info of openids from it in chunks of 100,
+// with at most concurrency requests at once, defaultConcurrency if not positive.
+// Openids are read as chunks are fetched, so that followers are never held at once.
+// handle is called for each chunk, never at the same time. Error of it is returned.
+func (mp *MP) FetchUserInfo(it *OpenIDIterator, lang string, concurrency, timeout int, handle UserInfoHandler) error {
+	lang, err := checkLang(lang)
+	if err != nil {
+		return err
+	}
+
+	return fetchUserInfo(it, concurrency, func(openIDs []string) ([]*wx.UserInfo, error) {
+		return mp.BatchGetUserInfo(openIDs, lang, timeout)
+	}, handle)
+}
+
+func fetchUserInfo(it *OpenIDIterator, concurrency int, batchGet func(openIDs []string) ([]*wx.UserInfo, error), handle UserInfoHandler) error {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	chunks := make(chan []string)
+	go func() {
+		defer close(chunks)
+		c := make([]string, 0, maxBatchGetUsers)
+		for it.Next() {
+			c = append(c, it.OpenID())
+			if len(c) == maxBatchGetUsers {
+				chunks <- c
+				c = make([]string, 0, maxBatchGetUsers)
+			}
+		}
+		if len(c) > 0 {
+			chunks <- c
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				infos, err := batchGet(c)
+				mu.Lock()
+				handle(infos, c, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return it.Err()
+}
+
+// UpdateRemark of user, and user.Remark is updated on success.
+// https://api.weixin.qq.com/cgi-bin/user/info/updateremark?access_token=ACCESS_TOKEN
+func (mp *MP) UpdateRemark(user *wx.UserInfo, remark string, timeout int) error {
+	if len(user.OpenID) <= 0 {
+		return wx.ParameterError{InvalidParameter: "openID"}
+	}
+	if utf8.RuneCountInString(remark) >= maxRemarkLen {
+		return wx.ParameterError{InvalidParameter: "remark"}
+	}
+
+	body := struct {
+		OpenID string `json:"openid"`
+		Remark string `json:"remark"`
+	}{user.OpenID, remark}
+
+	err := mp.postJSON(updateRemarkPath, body, nil, timeout)
+	if err != nil {
+		return err
+	}
+	user.Remark = remark
+	return nil
+}
+
+// checkLang defaults to wx.LangCN
+func checkLang(lang string) (string, error) {
+	if len(lang) <= 0 {
+		return wx.LangCN, nil
+	}
+	if lang != wx.LangCN && lang != wx.LangEN && lang != wx.LangTW {
+		return "", wx.ParameterError{InvalidParameter: "lang"}
+	}
+	return lang, nil
+}
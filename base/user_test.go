@@ -0,0 +1,198 @@
+package base
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+func TestFetchUserInfo(t *testing.T) {
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	var sizes []int
+	batchGet := func(openIDs []string) ([]*wx.UserInfo, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		sizes = append(sizes, len(openIDs))
+		mu.Unlock()
+		if openIDs[0] == "openid300" {
+			return nil, fmt.Errorf("fetch failed")
+		}
+		infos := make([]*wx.UserInfo, 0, len(openIDs))
+		for _, id := range openIDs {
+			infos = append(infos, &wx.UserInfo{OpenID: id})
+		}
+		return infos, nil
+	}
+
+	fetched := make(map[string]bool)
+	var failed []string
+	it := NewOpenIDIterator("", fakePages(350, 30, -1))
+	err := fetchUserInfo(it, 2, batchGet, func(infos []*wx.UserInfo, openIDs []string, err error) {
+		if err != nil {
+			failed = append(failed, openIDs...)
+			return
+		}
+		for _, info := range infos {
+			fetched[info.OpenID] = true
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Ints(sizes)
+	if fmt.Sprint(sizes) != "[50 100 100 100]" {
+		t.Errorf("unexpected chunk sizes: %v", sizes)
+	}
+	if maxInFlight != 2 {
+		t.Errorf("%d requests at once, expect 2", maxInFlight)
+	}
+	if len(fetched) != 300 || len(failed) != 50 || failed[0] != "openid300" {
+		t.Errorf("fetched %d, failed %d", len(fetched), len(failed))
+	}
+
+	// error of iterator returned after openids read
+	fetched = make(map[string]bool)
+	it = NewOpenIDIterator("", fakePages(350, 30, 120))
+	err = fetchUserInfo(it, 2, batchGet, func(infos []*wx.UserInfo, openIDs []string, err error) {
+		for _, info := range infos {
+			fetched[info.OpenID] = true
+		}
+	})
+	if err == nil || len(fetched) != 120 {
+		t.Errorf("fetched %d, error %v", len(fetched), err)
+	}
+}
+
+func TestFetchUserInfoRequests(t *testing.T) {
+	api, stop := serveFakeAPI(func(r *apiRequest) string {
+		var body struct {
+			UserList []struct {
+				OpenID string `json:"openid"`
+			} `json:"user_list"`
+		}
+		json.Unmarshal(r.Body, &body)
+		var resp struct {
+			UserInfoList []wx.UserInfo `json:"user_info_list"`
+		}
+		for _, u := range body.UserList {
+			resp.UserInfoList = append(resp.UserInfoList, wx.UserInfo{OpenID: u.OpenID})
+		}
+		data, _ := json.Marshal(resp)
+		return string(data)
+	})
+	defer stop()
+
+	mp := newTestMP()
+	n := 0
+	err := mp.FetchUserInfo(NewOpenIDSliceIterator(openIDs(150)), wx.LangEN, 0, 0, func(infos []*wx.UserInfo, openIDs []string, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		n += len(infos)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 150 {
+		t.Errorf("fetched %d users", n)
+	}
+	requests := api.served(userinfoBatchGetPath)
+	if len(requests) != 2 {
+		t.Fatalf("%d batch get requests", len(requests))
+	}
+	for _, r := range requests {
+		if r.Query.Get("access_token") != "ACCESS_TOKEN" {
+			t.Error("unexpected query: ", r.Query)
+		}
+		var body struct {
+			UserList []struct {
+				Lang string `json:"lang"`
+			} `json:"user_list"`
+		}
+		json.Unmarshal(r.Body, &body)
+		if body.UserList[0].Lang != wx.LangEN {
+			t.Errorf("unexpected body: %s", r.Body)
+		}
+	}
+
+	if err = mp.FetchUserInfo(NewOpenIDSliceIterator(openIDs(1)), "fr", 0, 0, nil); err == nil {
+		t.Error("expect error of invalid lang")
+	}
+	for _, n := range []int{0, 101} {
+		if _, err = mp.BatchGetUserInfo(openIDs(n), "", 0); err == nil {
+			t.Errorf("expect error batch getting %d users", n)
+		}
+	}
+	if len(api.served(userinfoBatchGetPath)) != 2 {
+		t.Error("invalid requests sent")
+	}
+}
+
+func TestGetFollowers(t *testing.T) {
+	api, stop := serveFakeAPI(func(r *apiRequest) string {
+		switch r.Query.Get("next_openid") {
+		case "":
+			return `{"total":3,"count":2,"data":{"openid":["openid0","openid1"]},"next_openid":"openid1"}`
+		case "openid1":
+			return `{"total":3,"count":1,"data":{"openid":["openid2"]},"next_openid":"openid2"}`
+		}
+		return `{"total":3,"count":0,"next_openid":""}`
+	})
+	defer stop()
+
+	it := newTestMP().FollowerIterator("", 0)
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.OpenID())
+	}
+	if it.Err() != nil || fmt.Sprint(ids) != "[openid0 openid1 openid2]" {
+		t.Errorf("iterated %v, error %v", ids, it.Err())
+	}
+	if requests := api.served(followerListPath); len(requests) != 3 || requests[1].Query.Get("next_openid") != "openid1" {
+		t.Errorf("%d requests", len(requests))
+	}
+}
+
+func TestCheckLang(t *testing.T) {
+	for _, c := range []struct {
+		lang   string
+		expect string
+		ok     bool
+	}{
+		{"", wx.LangCN, true},
+		{wx.LangCN, wx.LangCN, true},
+		{wx.LangTW, wx.LangTW, true},
+		{wx.LangEN, wx.LangEN, true},
+		{"zh_HK", "", false},
+	} {
+		lang, err := checkLang(c.lang)
+		if lang != c.expect || (err == nil) != c.ok {
+			t.Errorf("%q: got %q, error %v", c.lang, lang, err)
+		}
+	}
+}
+
+func openIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("openid%d", i)
+	}
+	return ids
+}
@@ -0,0 +1,44 @@
+package broadcast
+
+import (
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// StatusFeed feeds delivery status from TEMPLATESENDJOBFINISH events into Store.
+type StatusFeed struct {
+	Store Store
+
+	// Errors receives errors when updating delivery status if set,
+	// errors are dropped if nobody is receiving.
+	Errors chan error
+}
+
+// Handle updates delivery status in background, messages are passed to next handler if not nil.
+func (f *StatusFeed) Handle(next message.MessageHandler) message.MessageHandler {
+	return func(msg *message.Message) *message.Message {
+		if e, ok := msg.Content.(*message.Event); ok && e.Event == message.EventTemplateSendJobFinish {
+			go func() {
+				err := f.Store.UpdateStatus(e.MsgID, e.Status, msg.CreateTime)
+				if err != nil {
+					f.notifyError(err)
+				}
+			}()
+		}
+
+		if next == nil {
+			return nil
+		}
+		return next(msg)
+	}
+}
+
+func (f *StatusFeed) notifyError(err error) {
+	if f.Errors == nil {
+		return
+	}
+	select {
+	case f.Errors <- wx.NotifyError{Err: err}:
+	default:
+	}
+}
@@ -0,0 +1,262 @@
+package broadcast
+
+// send a template message to many recipients: openids are read from source
+// in batches, recipients tried in previous runs are skipped, and messages are
+// sent by workers under a rate limit, retrying transient errors.
+// A recipient is saved as sending before the request, so that a crashed run
+// resumes without sending twice, at the cost of recipients in flight left unsent.
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/MenInBack/weshin/base"
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	lookupBatch          = 100
+	defaultRetryInterval = time.Second
+)
+
+// Sender of template messages, implemented by *base.MP.
+type Sender interface {
+	SendTemplateMessage(msg *base.TemplateMessage, timeout int) (msgID int64, err error)
+}
+
+// Source of recipient openids, such as *base.OpenIDIterator.
+type Source interface {
+	Next() bool
+	OpenID() string
+	Err() error
+}
+
+// MessageFunc builds message to recipient.
+type MessageFunc func(openID string) (*base.TemplateMessage, error)
+
+// Static sends the same message to all recipients.
+func Static(msg *base.TemplateMessage) MessageFunc {
+	return func(openID string) (*base.TemplateMessage, error) {
+		m := *msg
+		m.ToUser = openID
+		return &m, nil
+	}
+}
+
+// Job broadcasts template message to recipients from Source.
+type Job struct {
+	ID      string // results are saved by job id, the same id resumes the job
+	Sender  Sender
+	Store   Store
+	Message MessageFunc
+
+	Rate          int // messages per second, unlimited if zero
+	Concurrency   int // workers sending, 1 if zero
+	Retries       int // of transient errors for each recipient
+	RetryInterval time.Duration
+	Timeout       int
+}
+
+// Run the job until source exhausted, recipients tried in previous runs are skipped.
+// The run stops at the first error of source or Store, results saved are kept for resuming.
+func (j *Job) Run(source Source) (*Stats, error) {
+	if len(j.ID) <= 0 {
+		return nil, wx.ParameterError{InvalidParameter: "job id"}
+	}
+	if j.Message == nil {
+		return nil, wx.ParameterError{InvalidParameter: "message"}
+	}
+
+	r := &run{
+		Job:  j,
+		stop: make(chan struct{}),
+	}
+	if j.Rate > 0 {
+		t := time.NewTicker(time.Second / time.Duration(j.Rate))
+		defer t.Stop()
+		r.tick = t.C
+	}
+
+	recipients := make(chan string)
+	var wg sync.WaitGroup
+	workers := j.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for openID := range recipients {
+				if err := r.send(openID); err != nil {
+					r.fail(err)
+				}
+			}
+		}()
+	}
+
+	r.produce(source, recipients)
+	close(recipients)
+	wg.Wait()
+
+	return &r.stats, r.err
+}
+
+// run holds state of a running job.
+type run struct {
+	*Job
+
+	tick <-chan time.Time
+	stop chan struct{}
+
+	mu    sync.Mutex
+	stats Stats
+	err   error
+}
+
+func (r *run) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+		close(r.stop)
+	}
+}
+
+func (r *run) count(f func(*Stats)) {
+	r.mu.Lock()
+	f(&r.stats)
+	r.mu.Unlock()
+}
+
+// produce feeds recipients not tried yet until source exhausted or run stopped.
+func (r *run) produce(source Source, recipients chan<- string) {
+	seen := make(map[string]bool)
+	batch := make([]string, 0, lookupBatch)
+
+	flush := func() bool {
+		results, err := r.Store.LookupResults(r.ID, batch)
+		if err != nil {
+			r.fail(err)
+			return false
+		}
+		for _, openID := range batch {
+			if _, ok := results[openID]; ok {
+				r.count(func(s *Stats) { s.Skipped++ })
+				continue
+			}
+			select {
+			case recipients <- openID:
+			case <-r.stop:
+				return false
+			}
+		}
+		batch = batch[:0]
+		return true
+	}
+
+	for source.Next() {
+		openID := source.OpenID()
+		if seen[openID] {
+			continue
+		}
+		seen[openID] = true
+		r.count(func(s *Stats) { s.Recipients++ })
+
+		batch = append(batch, openID)
+		if len(batch) >= lookupBatch && !flush() {
+			return
+		}
+	}
+	if err := source.Err(); err != nil {
+		r.fail(err)
+		return
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+}
+
+// send to recipient with retries, error returned only if result not saved.
+func (r *run) send(openID string) error {
+	if !r.wait(0) {
+		return nil
+	}
+
+	result := &Result{
+		OpenID:   openID,
+		State:    StateSending,
+		UpdateAt: time.Now().Unix(),
+	}
+	msg, err := r.Message(openID)
+	if err == nil {
+		err = r.Store.SaveResult(r.ID, result)
+		if err != nil {
+			return err
+		}
+
+		interval := r.RetryInterval
+		if interval <= 0 {
+			interval = defaultRetryInterval
+		}
+		for i := 0; ; i++ {
+			result.MsgID, err = r.Sender.SendTemplateMessage(msg, r.Timeout)
+			if err == nil || i >= r.Retries || !Transient(err) {
+				break
+			}
+			r.count(func(s *Stats) { s.Retried++ })
+			if !r.wait(interval << uint(i)) {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		result.State = StateFailed
+		result.Error = err.Error()
+		r.count(func(s *Stats) { s.Failed++ })
+	} else {
+		result.State = StateSent
+		r.count(func(s *Stats) { s.Sent++ })
+	}
+	result.UpdateAt = time.Now().Unix()
+	return r.Store.SaveResult(r.ID, result)
+}
+
+// wait for d and then rate limit, false if run stopped.
+func (r *run) wait(d time.Duration) bool {
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.stop:
+			return false
+		}
+	}
+	if r.tick != nil {
+		select {
+		case <-r.tick:
+		case <-r.stop:
+			return false
+		}
+	}
+	return true
+}
+
+// Transient tells whether error is worth retrying:
+// network errors, server errors, system busy and frequency limit of wechat.
+func Transient(err error) bool {
+	switch e := err.(type) {
+	case *wx.WechatError:
+		return e.ErrCode == -1 || e.ErrCode == 45011
+	case wx.WechatError:
+		return e.ErrCode == -1 || e.ErrCode == 45011
+	case wx.HttpError:
+		return e.State >= 500
+	case net.Error:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MenInBack/weshin/base"
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+type sliceSource struct {
+	openIDs []string
+	i       int
+}
+
+func (s *sliceSource) Next() bool {
+	s.i++
+	return s.i <= len(s.openIDs)
+}
+
+func (s *sliceSource) OpenID() string { return s.openIDs[s.i-1] }
+func (s *sliceSource) Err() error     { return nil }
+
+type fakeSender struct {
+	mu       sync.Mutex
+	sent     map[string]int
+	busy     map[string]int // times to fail with system busy
+	rejected string
+	msgID    int64
+}
+
+func (s *fakeSender) SendTemplateMessage(msg *base.TemplateMessage, timeout int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy[msg.ToUser] > 0 {
+		s.busy[msg.ToUser]--
+		return 0, &wx.WechatError{ErrCode: -1, ErrMsg: "system error"}
+	}
+	if msg.ToUser == s.rejected {
+		return 0, &wx.WechatError{ErrCode: 43004, ErrMsg: "require subscribe"}
+	}
+	s.sent[msg.ToUser]++
+	s.msgID++
+	return s.msgID, nil
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	results map[string]*Result
+	failAt  int // fails SaveResult after n saves if positive
+	saves   int
+}
+
+func (s *memoryStore) LookupResults(jobID string, openIDs []string) (map[string]*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make(map[string]*Result)
+	for _, id := range openIDs {
+		if r, ok := s.results[jobID+":"+id]; ok {
+			results[id] = r
+		}
+	}
+	return results, nil
+}
+
+func (s *memoryStore) SaveResult(jobID string, r *Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saves++
+	if s.failAt > 0 && s.saves > s.failAt {
+		return errors.New("store down")
+	}
+	saved := *r
+	s.results[jobID+":"+r.OpenID] = &saved
+	return nil
+}
+
+func (s *memoryStore) UpdateStatus(msgID int64, status string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.results {
+		if r.MsgID == msgID {
+			r.Status = status
+			r.UpdateAt = at
+			return nil
+		}
+	}
+	return fmt.Errorf("msgid %d not found", msgID)
+}
+
+func TestJob(t *testing.T) {
+	var openIDs []string
+	for i := 0; i < 250; i++ {
+		openIDs = append(openIDs, fmt.Sprintf("openid%03d", i))
+	}
+	sender := &fakeSender{
+		sent:     make(map[string]int),
+		busy:     map[string]int{"openid201": 2, "openid202": 5},
+		rejected: "openid203",
+	}
+	store := &memoryStore{results: make(map[string]*Result), failAt: 300}
+	job := &Job{
+		ID:            "job1",
+		Sender:        sender,
+		Store:         store,
+		Message:       Static(base.NewTemplateMessage("", "template").Set("first", "hello")),
+		Concurrency:   4,
+		Retries:       2,
+		RetryInterval: time.Millisecond,
+	}
+
+	// crashed by store after some recipients
+	_, err := job.Run(&sliceSource{openIDs: append(openIDs, openIDs[:10]...)})
+	if err == nil {
+		t.Fatal("expect store error")
+	}
+
+	store.failAt = 0
+	stats, err := job.Run(&sliceSource{openIDs: openIDs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Recipients != 250 || stats.Skipped+stats.Sent+stats.Failed != 250 || stats.Skipped == 0 {
+		t.Errorf("stats: %+v", stats)
+	}
+
+	for id, n := range sender.sent {
+		if n != 1 {
+			t.Errorf("%s sent %d times", id, n)
+		}
+	}
+	for _, c := range []struct {
+		openID string
+		state  string
+	}{
+		{"openid201", StateSent},   // retried
+		{"openid202", StateFailed}, // retries exhausted
+		{"openid203", StateFailed}, // not transient
+	} {
+		r := store.results["job1:"+c.openID]
+		if r == nil || r.State != c.state {
+			t.Errorf("%s: expect %s, got %+v", c.openID, c.state, r)
+		}
+	}
+	if sender.busy["openid202"] != 2 {
+		t.Error("expect 3 tries of openid202, left ", sender.busy["openid202"])
+	}
+
+	r := store.results["job1:openid201"]
+	done := make(chan struct{}, 2)
+	feed := &StatusFeed{Store: store, Errors: make(chan error, 1)}
+	h := feed.Handle(func(msg *message.Message) *message.Message {
+		done <- struct{}{}
+		return nil
+	})
+	h(&message.Message{
+		Meta:    message.Meta{MessageType: message.TypeEvent, CreateTime: 1500000000},
+		Content: &message.Event{Event: message.EventTemplateSendJobFinish, MsgID: r.MsgID, Status: StatusUserBlock},
+	})
+	<-done
+	var status string
+	for i := 0; i < 100 && status != StatusUserBlock; i++ {
+		time.Sleep(time.Millisecond)
+		store.mu.Lock()
+		status = r.Status
+		store.mu.Unlock()
+	}
+	if status != StatusUserBlock {
+		t.Error("delivery status not updated")
+	}
+
+	// status of unknown message
+	h(&message.Message{
+		Meta:    message.Meta{MessageType: message.TypeEvent, CreateTime: 1500000000},
+		Content: &message.Event{Event: message.EventTemplateSendJobFinish, MsgID: -1, Status: StatusSuccess},
+	})
+	select {
+	case err := <-feed.Errors:
+		if _, ok := err.(wx.NotifyError); !ok {
+			t.Error("unexpected error: ", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expect error of unknown msgid")
+	}
+}
+
+func TestTransient(t *testing.T) {
+	for i, c := range []struct {
+		err       error
+		transient bool
+	}{
+		{&wx.WechatError{ErrCode: -1}, true},
+		{&wx.WechatError{ErrCode: 45011}, true},
+		{&wx.WechatError{ErrCode: 40003}, false},
+		{wx.HttpError{State: 502}, true},
+		{wx.HttpError{State: 404}, false},
+		{wx.ParameterError{InvalidParameter: "toUser"}, false},
+	} {
+		if Transient(c.err) != c.transient {
+			t.Errorf("case %d: expect transient %v", i, c.transient)
+		}
+	}
+}
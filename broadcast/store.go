@@ -0,0 +1,44 @@
+package broadcast
+
+// Store holds per recipient results of broadcast jobs.
+type Store interface {
+	// LookupResults returns results of openids in job, openids not tried yet are omitted.
+	LookupResults(jobID string, openIDs []string) (map[string]*Result, error)
+	// SaveResult of recipient in job, replacing the one saved.
+	SaveResult(jobID string, r *Result) error
+	// UpdateStatus of result sent with msgID, reported by TEMPLATESENDJOBFINISH event.
+	UpdateStatus(msgID int64, status string, at int64) error
+}
+
+// state of result
+const (
+	StateSending = "sending" // saved before sending, not resent on resume
+	StateSent    = "sent"
+	StateFailed  = "failed"
+)
+
+// delivery status of TEMPLATESENDJOBFINISH event
+const (
+	StatusSuccess      = "success"
+	StatusUserBlock    = "failed:user block"
+	StatusSystemFailed = "failed: system failed"
+)
+
+// Result of sending to a recipient.
+type Result struct {
+	OpenID   string `json:"openID"`
+	State    string `json:"state"`
+	MsgID    int64  `json:"msgID,omitempty"`
+	Error    string `json:"error,omitempty"`  // last error if failed
+	Status   string `json:"status,omitempty"` // delivery status, empty until event received
+	UpdateAt int64  `json:"updateAt"`
+}
+
+// Stats of a run.
+type Stats struct {
+	Recipients int `json:"recipients"` // openids from source
+	Sent       int `json:"sent"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped"` // tried in previous runs
+	Retried    int `json:"retried"` // retries of transient errors
+}
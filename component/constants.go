@@ -17,3 +17,14 @@ const (
 	NotifyTypeAuthorized       = "authorized"
 	NotifyTypeUpdateAuthorized = "updateauthorized"
 )
+
+// full network release test
+var releaseTestAppIDs = []string{
+	"wx570bc396a51b8ff8", // official account
+	"wxd101a85aa106f53e", // mini program
+}
+
+const (
+	releaseTestText          = "TESTCOMPONENT_MSG_TYPE_TEXT"
+	releaseTestQueryAuthCode = "QUERY_AUTH_CODE:"
+)
@@ -0,0 +1,189 @@
+package component
+
+// message push for authorizers
+// https://open.weixin.qq.com/cgi-bin/showdocument?action=dir_list&t=resource/res_list&verify=1&id=open1453779503&token=&lang=zh_CN
+// full network release test
+// https://open.weixin.qq.com/cgi-bin/showdocument?action=dir_list&t=resource/res_list&verify=1&id=open1419318611&token=&lang=zh_CN
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// MessageProxy receives messages of all authorizers at /{APPID}/callback,
+// which are encrypted with component key, and routes them to handler of the authorizer.
+// Full network release test is responded automatically.
+type MessageProxy struct {
+	*Component
+
+	// DefaultHandler for authorizers without handler set by HandleAuthorizer.
+	DefaultHandler message.MessageHandler
+	// Dedupe, Deadline, AllowList and Errors work as in message.Server,
+	// with authorizer access token for customer service api.
+	Dedupe    *message.Deduplicator
+	Deadline  time.Duration
+	AllowList *message.IPAllowList
+	Errors    chan error
+
+	mu       sync.RWMutex
+	handlers map[string]message.MessageHandler
+
+	authorize  func(code string) (accessToken string, err error)                       // MPAuthorize if nil
+	sendCustom func(accessToken string, msg *message.CustomMessage, timeout int) error // message.SendCustomMessage if nil
+}
+
+// HandleAuthorizer sets message handler for authorizer.
+func (p *MessageProxy) HandleAuthorizer(authorizerAppID string, handler message.MessageHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.handlers == nil {
+		p.handlers = make(map[string]message.MessageHandler)
+	}
+	p.handlers[authorizerAppID] = handler
+}
+
+// handler of authorizer, with authorizer appid in message context.
+func (p *MessageProxy) handler(authorizerAppID string) message.MessageHandler {
+	if isReleaseTestAccount(authorizerAppID) {
+		return p.releaseTestHandler(authorizerAppID)
+	}
+
+	p.mu.RLock()
+	h, ok := p.handlers[authorizerAppID]
+	p.mu.RUnlock()
+	if !ok {
+		h = p.DefaultHandler
+	}
+	if h == nil {
+		return nil
+	}
+
+	return func(msg *message.Message) *message.Message {
+		ctx := message.WithAuthorizerAppID(msg.Context(), authorizerAppID)
+		return h(msg.WithContext(ctx))
+	}
+}
+
+func (p *MessageProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	dir, file := path.Split(strings.TrimSuffix(req.URL.Path, "/"))
+	appID := path.Base(dir)
+	if file != "callback" || appID == "/" || appID == "." {
+		http.NotFound(w, req)
+		return
+	}
+	// messages of authorizers are always encrypted with component key
+	if req.Method == http.MethodPost && req.URL.Query().Get("encrypt_type") != message.EncryptTypeAES {
+		http.Error(w, "message not encrypted", http.StatusBadRequest)
+		return
+	}
+
+	s := &message.Server{
+		AppID:          p.AppID,
+		Token:          p.SignatureToken,
+		EncodingAESKey: p.EncodingAESKey,
+		Handler:        p.handler(appID),
+		Dedupe:         p.Dedupe,
+		Deadline:       p.Deadline,
+		AllowList:      p.AllowList,
+		TokenStorage:   authorizerTokenStorage{p.Component, appID},
+		Errors:         p.Errors,
+	}
+	s.ServeHTTP(w, req)
+}
+
+// authorizerTokenStorage reads authorizer access token for message.Server,
+// tokens are set by Component itself.
+type authorizerTokenStorage struct {
+	*Component
+	authorizerAppID string
+}
+
+func (s authorizerTokenStorage) GetAccessToken() string {
+	return s.GetAuthorizerToken(s.authorizerAppID)
+}
+
+func (s authorizerTokenStorage) SetAccessToken(token string, expiresIn int64) {}
+
+func isReleaseTestAccount(appID string) bool {
+	for _, id := range releaseTestAppIDs {
+		if id == appID {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseTestHandler replies messages of full network release test:
+// events with "{Event}from_callback",
+// TESTCOMPONENT_MSG_TYPE_TEXT with "TESTCOMPONENT_MSG_TYPE_TEXT_callback",
+// QUERY_AUTH_CODE:{code} by customer service api with "{code}_from_api".
+func (p *MessageProxy) releaseTestHandler(authorizerAppID string) message.MessageHandler {
+	return func(msg *message.Message) *message.Message {
+		var content string
+		switch c := msg.Content.(type) {
+		case *message.Event:
+			content = c.Event + "from_callback"
+		case *message.Text:
+			switch {
+			case c.Content == releaseTestText:
+				content = releaseTestText + "_callback"
+			case strings.HasPrefix(c.Content, releaseTestQueryAuthCode):
+				code := strings.TrimPrefix(c.Content, releaseTestQueryAuthCode)
+				go p.replyQueryAuthCode(msg.FromUserName, code)
+				return nil
+			default:
+				return nil
+			}
+		default:
+			return nil
+		}
+
+		return &message.Message{
+			Meta:    message.Meta{MessageType: message.TypeText},
+			Content: &message.Text{Content: content},
+		}
+	}
+}
+
+func (p *MessageProxy) replyQueryAuthCode(openID, code string) {
+	authorize, send := p.authorize, p.sendCustom
+	if authorize == nil {
+		authorize = func(code string) (string, error) {
+			auth, err := p.MPAuthorize(code, 0)
+			if err != nil {
+				return "", err
+			}
+			return auth.AuthorizationToken.AccessToken, nil
+		}
+	}
+	if send == nil {
+		send = message.SendCustomMessage
+	}
+
+	accessToken, err := authorize(code)
+	if err != nil {
+		p.notifyError(err)
+		return
+	}
+
+	err = send(accessToken, message.NewCustomText(openID, code+"_from_api"), 0)
+	if err != nil {
+		p.notifyError(err)
+	}
+}
+
+func (p *MessageProxy) notifyError(err error) {
+	if p.Errors == nil {
+		return
+	}
+	select {
+	case p.Errors <- wx.NotifyError{Err: err}:
+	default:
+	}
+}
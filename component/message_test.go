@@ -0,0 +1,161 @@
+package component
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MenInBack/weshin/crypto"
+	"github.com/MenInBack/weshin/message"
+)
+
+const (
+	testAppID          = "wx2c2769f8efd9abc2"
+	testToken          = "spamtest"
+	testEncodingAESKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	testNonce          = "1320562132"
+	testTimestamp      = "1409735669"
+
+	testTextXML  = `<xml><ToUserName><![CDATA[gh_10f6c3c3ac5a]]></ToUserName><FromUserName><![CDATA[oyORnuP8q7ou2gfYjqLzSIWZf0rs]]></FromUserName><CreateTime>1409735668</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[%s]]></Content><MsgId>6054768590064713728</MsgId></xml>`
+	testEventXML = `<xml><ToUserName><![CDATA[gh_10f6c3c3ac5a]]></ToUserName><FromUserName><![CDATA[oyORnuP8q7ou2gfYjqLzSIWZf0rs]]></FromUserName><CreateTime>1409735668</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[LOCATION]]></Event></xml>`
+)
+
+func newTestProxy() *MessageProxy {
+	return &MessageProxy{Component: &Component{
+		AppID:          testAppID,
+		SignatureToken: testToken,
+		EncodingAESKey: testEncodingAESKey,
+	}}
+}
+
+// push message to authorizer encrypted with component key, returns status and decrypted reply.
+func pushAuthorizerMessage(t *testing.T, p *MessageProxy, path, body string, encrypt bool) (int, *message.Message) {
+	query := "?signature=" + string(crypto.Signature([]string{testTimestamp, testNonce, testToken})) +
+		"&timestamp=" + testTimestamp + "&nonce=" + testNonce
+	if encrypt {
+		encoding, err := crypto.New(testEncodingAESKey, testToken, testAppID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := encoding.Encrypt([]byte(body), testNonce, testTimestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var encrypted struct {
+			MsgSignature string `xml:"MsgSignature"`
+		}
+		err = xml.Unmarshal(data, &encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query += "&encrypt_type=aes&msg_signature=" + encrypted.MsgSignature
+		body = string(data)
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("POST", path+query, strings.NewReader(body)))
+	if w.Code != http.StatusOK || w.Body.String() == "success" {
+		return w.Code, nil
+	}
+
+	encoding, err := crypto.New(testEncodingAESKey, testToken, testAppID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encoding.Decrypt(w.Body.Bytes(), "", "", "")
+	if err != nil {
+		t.Fatal("decrypt reply: ", err)
+	}
+	reply, err := message.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, reply
+}
+
+func replyText(reply *message.Message) string {
+	if reply == nil {
+		return ""
+	}
+	if text, ok := reply.Content.(*message.Text); ok {
+		return text.Content
+	}
+	return ""
+}
+
+func TestMessageProxyRouting(t *testing.T) {
+	p := newTestProxy()
+	echo := func(name string) message.MessageHandler {
+		return func(msg *message.Message) *message.Message {
+			return &message.Message{Content: &message.Text{
+				Content: name + ":" + message.AuthorizerAppID(msg.Context()),
+			}}
+		}
+	}
+	p.HandleAuthorizer("wxauthorizer1", echo("handler"))
+	p.DefaultHandler = echo("default")
+
+	for _, c := range []struct {
+		path  string
+		code  int
+		reply string
+	}{
+		{"/wxauthorizer1/callback", http.StatusOK, "handler:wxauthorizer1"},
+		{"/wxauthorizer2/callback/", http.StatusOK, "default:wxauthorizer2"},
+		{"/wxauthorizer1/notify", http.StatusNotFound, ""},
+		{"/callback", http.StatusNotFound, ""},
+	} {
+		code, reply := pushAuthorizerMessage(t, p, c.path, fmt.Sprintf(testTextXML, "hello"), true)
+		if code != c.code || replyText(reply) != c.reply {
+			t.Errorf("%s: replied %d %q", c.path, code, replyText(reply))
+		}
+	}
+
+	// messages of authorizers are never plain
+	if code, _ := pushAuthorizerMessage(t, p, "/wxauthorizer1/callback", fmt.Sprintf(testTextXML, "hello"), false); code != http.StatusBadRequest {
+		t.Error("expect plain message rejected, got ", code)
+	}
+}
+
+func TestMessageProxyReleaseTest(t *testing.T) {
+	sent := make(chan *message.CustomMessage, 1)
+	p := newTestProxy()
+	p.authorize = func(code string) (string, error) {
+		return "token_of_" + code, nil
+	}
+	p.sendCustom = func(accessToken string, msg *message.CustomMessage, timeout int) error {
+		if accessToken != "token_of_queryauthcode" {
+			t.Error("unexpected access token: ", accessToken)
+		}
+		sent <- msg
+		return nil
+	}
+	path := "/" + releaseTestAppIDs[0] + "/callback"
+
+	_, reply := pushAuthorizerMessage(t, p, path, testEventXML, true)
+	if replyText(reply) != "LOCATIONfrom_callback" {
+		t.Errorf("unexpected reply of event: %q", replyText(reply))
+	}
+
+	_, reply = pushAuthorizerMessage(t, p, path, fmt.Sprintf(testTextXML, releaseTestText), true)
+	if replyText(reply) != "TESTCOMPONENT_MSG_TYPE_TEXT_callback" {
+		t.Errorf("unexpected reply of text: %q", replyText(reply))
+	}
+
+	code, reply := pushAuthorizerMessage(t, p, path, fmt.Sprintf(testTextXML, releaseTestQueryAuthCode+"queryauthcode"), true)
+	if code != http.StatusOK || reply != nil {
+		t.Errorf("expect empty reply of query auth code, got %d %+v", code, reply)
+	}
+	select {
+	case msg := <-sent:
+		if msg.ToUser != "oyORnuP8q7ou2gfYjqLzSIWZf0rs" || msg.Text == nil || msg.Text.Content != "queryauthcode_from_api" {
+			t.Errorf("unexpected custom message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("query auth code not replied by customer service api")
+	}
+}
@@ -65,8 +65,8 @@ func (mc *MessageCrypto) Encrypt(msg []byte, nonce, timestamp string) (data []by
 	mode.CryptBlocks(pad, pad)
 
 	//3. base64Encode
-	buf := make([]byte, base64.RawStdEncoding.EncodedLen(len(pad)))
-	base64.RawStdEncoding.Encode(buf, pad)
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(pad)))
+	base64.StdEncoding.Encode(buf, pad)
 
 	//4. compute signature
 	if len(nonce) > 0 {
@@ -1,6 +1,8 @@
 package crypto
 
 import (
+	"encoding/base64"
+	"encoding/xml"
 	"fmt"
 	"testing"
 )
@@ -43,3 +45,45 @@ func TestDecrypt(t *testing.T) {
 	fmt.Println(string(r))
 	t.Log(string(r))
 }
+
+func TestEncryptDecrypt(t *testing.T) {
+	encoding, err := New(encodingAESKey, token, appid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decrypt sample pushed by wechat, then encrypt it back as a reply
+	msg, err := encoding.Decrypt([]byte(from_xml), msg_sign, nonce, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encoding.Encrypt(msg, nonce, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted := new(encryptedMessage)
+	err = xml.Unmarshal(data, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wechat decodes padded base64 only
+	if _, err := base64.StdEncoding.DecodeString(encrypted.Encrypt.Data); err != nil {
+		t.Error("invalid base64 of encrypted reply: ", err)
+	}
+	if encrypted.ToUserName.Data != "oyORnuP8q7ou2gfYjqLzSIWZf0rs" {
+		t.Error("unexpected ToUserName: ", encrypted.ToUserName.Data)
+	}
+
+	decoding, err := New(encodingAESKey, token, appid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := decoding.Decrypt(data, encrypted.MsgSignature.Data, nonce, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(r) != string(msg) {
+		t.Errorf("round trip mismatch: %s", r)
+	}
+}
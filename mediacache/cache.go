@@ -0,0 +1,155 @@
+package mediacache
+
+// reuse temporary media uploaded: media is keyed by appid, type and sha256 of content,
+// the media id cached is returned until close to expiry, then content is uploaded again.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/MenInBack/weshin/base"
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	// MediaTTL of temporary media.
+	MediaTTL             = 3 * 24 * time.Hour
+	defaultRefreshBefore = time.Hour
+)
+
+// Uploader of temporary media, implemented by *base.MP.
+// Media ids are valid for the account uploading only.
+type Uploader interface {
+	GetAppID() string
+	UploadMedia(mediaType, filename string, r io.Reader, timeout int) (*base.Media, error)
+}
+
+// Store holds media uploaded by key.
+type Store interface {
+	// Get returns media of key, nil if not found.
+	Get(key string) (*Entry, error)
+	Set(key string, e *Entry) error
+	Delete(key string) error
+}
+
+// Entry of media uploaded.
+type Entry struct {
+	MediaID   string `json:"mediaID"`
+	CreatedAt int64  `json:"createdAt"` // unix time returned by upload
+}
+
+// Cache uploads media through Uploader, reusing media id in Store,
+// which may be shared by caches of several accounts.
+type Cache struct {
+	Uploader Uploader
+	Store    Store
+	Timeout  int
+	// RefreshBefore uploads again if media expires within it, an hour if zero.
+	RefreshBefore time.Duration
+
+	flight wx.Flight
+}
+
+// Key of media content uploaded by account of appID.
+func Key(appID, mediaType string, data []byte) string {
+	sum := sha256.Sum256(data)
+	return appID + ":" + mediaType + ":" + hex.EncodeToString(sum[:])
+}
+
+// Upload reads content from r into memory to hash it, returns media id cached or uploaded.
+func (c *Cache) Upload(mediaType, filename string, r io.Reader) (mediaID string, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return c.UploadBytes(mediaType, filename, data)
+}
+
+// UploadBytes returns media id cached for data, or uploads it.
+// Concurrent uploads of the same content share one request.
+func (c *Cache) UploadBytes(mediaType, filename string, data []byte) (mediaID string, err error) {
+	key := Key(c.Uploader.GetAppID(), mediaType, data)
+
+	v, err := c.flight.Do(key, func() (interface{}, error) {
+		return c.upload(key, mediaType, filename, data)
+	})
+	mediaID, _ = v.(string)
+	return mediaID, err
+}
+
+func (c *Cache) upload(key, mediaType, filename string, data []byte) (string, error) {
+	e, err := c.Store.Get(key)
+	if err != nil {
+		return "", err
+	}
+	if e != nil && c.valid(e, time.Now()) {
+		return e.MediaID, nil
+	}
+
+	media, err := c.Uploader.UploadMedia(mediaType, filename, bytes.NewReader(data), c.Timeout)
+	if err != nil {
+		return "", err
+	}
+	createdAt := media.CreatedAt
+	if createdAt == 0 {
+		createdAt = time.Now().Unix()
+	}
+
+	err = c.Store.Set(key, &Entry{MediaID: media.MediaID, CreatedAt: createdAt})
+	if err != nil {
+		return "", err
+	}
+	return media.MediaID, nil
+}
+
+func (c *Cache) valid(e *Entry, now time.Time) bool {
+	before := c.RefreshBefore
+	if before <= 0 {
+		before = defaultRefreshBefore
+	}
+	return now.Before(time.Unix(e.CreatedAt, 0).Add(MediaTTL - before))
+}
+
+// Forget media of content, such as media id rejected as invalid.
+func (c *Cache) Forget(mediaType string, data []byte) error {
+	return c.Store.Delete(Key(c.Uploader.GetAppID(), mediaType, data))
+}
+
+// MemoryStore keeps media in memory, for single process.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]Entry)}
+}
+
+func (s *MemoryStore) Get(key string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(time.Unix(e.CreatedAt, 0).Add(MediaTTL)) {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (s *MemoryStore) Set(key string, e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = *e
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,81 @@
+package mediacache
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MenInBack/weshin/base"
+)
+
+type fakeUploader struct {
+	appID   string
+	uploads *int32 // shared by accounts
+}
+
+func (u *fakeUploader) GetAppID() string {
+	return u.appID
+}
+
+func (u *fakeUploader) UploadMedia(mediaType, filename string, r io.Reader, timeout int) (*base.Media, error) {
+	n := atomic.AddInt32(u.uploads, 1)
+	ioutil.ReadAll(r)
+	time.Sleep(10 * time.Millisecond)
+	return &base.Media{Type: mediaType, MediaID: fmt.Sprint("media", n), CreatedAt: time.Now().Unix()}, nil
+}
+
+func TestCache(t *testing.T) {
+	uploads := new(int32)
+	store := NewMemoryStore()
+	c := &Cache{Uploader: &fakeUploader{"wxappid1", uploads}, Store: store}
+	image := []byte("image content")
+
+	var wg sync.WaitGroup
+	ids := make([]string, 5)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], _ = c.UploadBytes(base.MediaImage, "a.jpg", image)
+		}(i)
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if id != "media1" {
+			t.Fatal("expect one upload shared, got ", ids)
+		}
+	}
+
+	// same content of another type
+	if id, _ := c.UploadBytes(base.MediaThumb, "a.jpg", image); id != "media2" {
+		t.Error("expect thumb uploaded, got ", id)
+	}
+
+	// close to expiry
+	key := Key("wxappid1", base.MediaImage, image)
+	store.Set(key, &Entry{MediaID: "media1", CreatedAt: time.Now().Add(-MediaTTL + 30*time.Minute).Unix()})
+	if id, _ := c.UploadBytes(base.MediaImage, "a.jpg", image); id != "media3" {
+		t.Error("expect uploaded again, got ", id)
+	}
+	if id, _ := c.UploadBytes(base.MediaImage, "a.jpg", image); id != "media3" {
+		t.Error("expect cached, got ", id)
+	}
+
+	c.Forget(base.MediaImage, image)
+	if id, _ := c.UploadBytes(base.MediaImage, "a.jpg", image); id != "media4" {
+		t.Error("expect uploaded after forgotten, got ", id)
+	}
+
+	// same content of another account sharing store
+	other := &Cache{Uploader: &fakeUploader{"wxappid2", uploads}, Store: store}
+	if id, _ := other.UploadBytes(base.MediaImage, "a.jpg", image); id != "media5" {
+		t.Error("expect uploaded by another account, got ", id)
+	}
+	if id, _ := c.UploadBytes(base.MediaImage, "a.jpg", image); id != "media4" {
+		t.Error("expect cached of account, got ", id)
+	}
+}
@@ -0,0 +1,235 @@
+package menusync
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MenInBack/weshin/base"
+)
+
+// action of menu change
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update" // conditional menu is deleted and added again
+	ActionDelete = "delete"
+)
+
+// Plan of changes to live menu, see Syncer.Plan.
+type Plan struct {
+	Default     *MenuChange   // nil if default menu unchanged
+	Conditional []*MenuChange // conditional menus to create, update or delete
+
+	live   *base.MenuInfo
+	config *base.MenuInfo
+}
+
+// MenuChange of a menu, with buttons changed in Diff.
+type MenuChange struct {
+	Action string
+	Menu   *base.Menu // menu in config, live menu for ActionDelete
+	MenuID int64      // live menu id of conditional menu to update or delete
+	Diff   []string   // "- " for removed, "+ " for added, "~ " for changed buttons
+}
+
+// Empty if nothing to change.
+func (p *Plan) Empty() bool {
+	return p.Default == nil && len(p.Conditional) == 0
+}
+
+// String returns the diff in readable lines.
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "menu is up to date\n"
+	}
+
+	var buf bytes.Buffer
+	if p.Default != nil {
+		fmt.Fprintf(&buf, "%s default menu\n", p.Default.Action)
+		for _, line := range p.Default.Diff {
+			fmt.Fprintf(&buf, "    %s\n", line)
+		}
+	}
+	for _, c := range p.Conditional {
+		fmt.Fprintf(&buf, "%s conditional menu [%s]", c.Action, describeRule(c.Menu.MatchRule))
+		if c.MenuID > 0 {
+			fmt.Fprintf(&buf, " menuid %d", c.MenuID)
+		}
+		buf.WriteByte('\n')
+		for _, line := range c.Diff {
+			fmt.Fprintf(&buf, "    %s\n", line)
+		}
+	}
+	return buf.String()
+}
+
+func diff(live *base.MenuInfo, config *base.MenuInfo) *Plan {
+	p := &Plan{
+		live:   live,
+		config: config,
+	}
+
+	if lines := diffButtons(live.Menu.Buttons, config.Menu.Buttons); len(lines) > 0 {
+		action := ActionUpdate
+		if len(live.Menu.Buttons) == 0 {
+			action = ActionCreate
+		}
+		p.Default = &MenuChange{
+			Action: action,
+			Menu:   &config.Menu,
+			Diff:   lines,
+		}
+	}
+
+	// conditional menus are identified by match rules
+	liveMenus := make(map[base.MatchRule]*base.Menu)
+	for i := range live.ConditionalMenu {
+		m := &live.ConditionalMenu[i]
+		liveMenus[normalizeRule(m.MatchRule)] = m
+	}
+	for i := range config.ConditionalMenu {
+		m := &config.ConditionalMenu[i]
+		rule := normalizeRule(m.MatchRule)
+		old, ok := liveMenus[rule]
+		delete(liveMenus, rule)
+		if !ok {
+			p.Conditional = append(p.Conditional, &MenuChange{
+				Action: ActionCreate,
+				Menu:   m,
+				Diff:   diffButtons(nil, m.Buttons),
+			})
+			continue
+		}
+		if lines := diffButtons(old.Buttons, m.Buttons); len(lines) > 0 {
+			p.Conditional = append(p.Conditional, &MenuChange{
+				Action: ActionUpdate,
+				Menu:   m,
+				MenuID: old.MenuID,
+				Diff:   lines,
+			})
+		}
+	}
+	for i := range live.ConditionalMenu {
+		m := &live.ConditionalMenu[i]
+		if _, ok := liveMenus[normalizeRule(m.MatchRule)]; !ok {
+			continue // kept
+		}
+		p.Conditional = append(p.Conditional, &MenuChange{
+			Action: ActionDelete,
+			Menu:   m,
+			MenuID: m.MenuID,
+			Diff:   diffButtons(m.Buttons, nil),
+		})
+	}
+
+	return p
+}
+
+// normalizeRule for comparing rules field by field,
+// numbers and group_id returned by menu/get are normalized by MatchRule.UnmarshalJSON.
+func normalizeRule(rule *base.MatchRule) base.MatchRule {
+	if rule == nil {
+		return base.MatchRule{}
+	}
+	return base.MatchRule{
+		TagID:              strings.TrimSpace(rule.TagID),
+		Sex:                strings.TrimSpace(rule.Sex),
+		Country:            strings.TrimSpace(rule.Country),
+		Province:           strings.TrimSpace(rule.Province),
+		City:               strings.TrimSpace(rule.City),
+		ClientPlatformType: strings.TrimSpace(rule.ClientPlatformType),
+		Language:           strings.TrimSpace(rule.Language),
+	}
+}
+
+// diffButtons compares buttons by position.
+func diffButtons(old, new []base.Button) []string {
+	oldButtons := flatten(old)
+	newButtons := flatten(new)
+
+	var lines []string
+	for _, b := range newButtons {
+		o, ok := find(oldButtons, b.path)
+		switch {
+		case !ok:
+			lines = append(lines, "+ "+b.path+" "+b.desc)
+		case o.desc != b.desc:
+			lines = append(lines, "~ "+b.path+" "+o.desc+" => "+b.desc)
+		}
+	}
+	for _, o := range oldButtons {
+		if _, ok := find(newButtons, o.path); !ok {
+			lines = append(lines, "- "+o.path+" "+o.desc)
+		}
+	}
+	return lines
+}
+
+type flatButton struct {
+	path string
+	desc string
+}
+
+func flatten(buttons []base.Button) []flatButton {
+	var flat []flatButton
+	for i, b := range buttons {
+		path := fmt.Sprintf("button[%d]", i)
+		flat = append(flat, flatButton{path, describeButton(&b)})
+		for j, sub := range b.SubButtons {
+			flat = append(flat, flatButton{fmt.Sprintf("%s.sub_button[%d]", path, j), describeButton(&sub)})
+		}
+	}
+	return flat
+}
+
+func find(buttons []flatButton, path string) (flatButton, bool) {
+	for _, b := range buttons {
+		if b.path == path {
+			return b, true
+		}
+	}
+	return flatButton{}, false
+}
+
+func describeButton(b *base.Button) string {
+	fields := []string{strconv.Quote(b.Name)}
+	if len(b.Type) > 0 {
+		fields = append(fields, b.Type)
+	}
+	for _, f := range []struct{ name, value string }{
+		{"key", b.Key},
+		{"url", b.URL},
+		{"media_id", b.MediaID},
+		{"article_id", b.ArticleID},
+		{"appid", b.AppID},
+		{"pagepath", b.PagePath},
+	} {
+		if len(f.value) > 0 {
+			fields = append(fields, f.name+"="+f.value)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func describeRule(rule *base.MatchRule) string {
+	if rule == nil {
+		return ""
+	}
+
+	var fields []string
+	for _, f := range []struct{ name, value string }{
+		{"tag_id", rule.TagID},
+		{"sex", rule.Sex},
+		{"country", rule.Country},
+		{"province", rule.Province},
+		{"city", rule.City},
+		{"client_platform_type", rule.ClientPlatformType},
+		{"language", rule.Language},
+	} {
+		if len(f.value) > 0 {
+			fields = append(fields, f.name+"="+f.value)
+		}
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,78 @@
+package menusync
+
+import (
+	"strings"
+	"testing"
+)
+
+const liveMenu = `{
+	"menu": {"button": [
+		{"type": "click", "name": "今日歌曲", "key": "V1001_TODAY_MUSIC"},
+		{"name": "菜单", "sub_button": [
+			{"type": "view", "name": "搜索", "url": "http://www.soso.com/"}
+		]}
+	]},
+	"conditionalmenu": [
+		{"button": [{"type": "click", "name": "男生", "key": "MALE"}], "matchrule": {"tag_id": "2"}, "menuid": 208396938},
+		{"button": [{"type": "click", "name": "女生", "key": "FEMALE"}], "matchrule": {"tag_id": "3"}, "menuid": 208396939}
+	]
+}`
+
+const desiredMenu = `{
+	"menu": {"button": [
+		{"type": "click", "name": "今日歌曲", "key": "V1002_TODAY_MUSIC"},
+		{"name": "菜单", "sub_button": [
+			{"type": "view", "name": "搜索", "url": "http://www.soso.com/"},
+			{"type": "scancode_push", "name": "扫码", "key": "SCAN"}
+		]}
+	]},
+	"conditionalmenu": [
+		{"button": [{"type": "click", "name": "男生", "key": "MALE"}], "matchrule": {"tag_id": "2"}},
+		{"button": [{"type": "click", "name": "新人", "key": "NEW"}], "matchrule": {"tag_id": "4"}}
+	]
+}`
+
+func TestPlan(t *testing.T) {
+	live, err := LoadConfig([]byte(liveMenu), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired, err := LoadConfig([]byte(desiredMenu), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := diff(live, live); !p.Empty() {
+		t.Error("expect empty plan, got:\n", p)
+	}
+
+	p := diff(live, desired)
+	t.Log("\n", p)
+	if p.Default == nil || len(p.Default.Diff) != 2 {
+		t.Fatalf("expect 2 changes of default menu, got %+v", p.Default)
+	}
+	if !strings.HasPrefix(p.Default.Diff[0], "~ button[0]") || !strings.HasPrefix(p.Default.Diff[1], "+ button[1].sub_button[1]") {
+		t.Error("unexpected diff of default menu: ", p.Default.Diff)
+	}
+	if len(p.Conditional) != 2 {
+		t.Fatalf("expect 2 changes of conditional menus, got %d", len(p.Conditional))
+	}
+	if c := p.Conditional[0]; c.Action != ActionCreate || c.Menu.MatchRule.TagID != "4" {
+		t.Errorf("expect conditional menu for tag 4 created, got %+v", c)
+	}
+	if c := p.Conditional[1]; c.Action != ActionDelete || c.MenuID != 208396939 {
+		t.Errorf("expect conditional menu 208396939 deleted, got %+v", c)
+	}
+
+	// rules returned by menu/get in numbers or group_id are the same as config
+	returned, err := LoadConfig([]byte(strings.NewReplacer(
+		`"matchrule": {"tag_id": "2"}`, `"matchrule": {"group_id": 2}`,
+		`"matchrule": {"tag_id": "3"}`, `"matchrule": {"tag_id": 3, "country": ""}`,
+	).Replace(liveMenu)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := diff(returned, live); !p.Empty() {
+		t.Error("expect empty plan, got:\n", p)
+	}
+}
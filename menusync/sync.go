@@ -0,0 +1,120 @@
+package menusync
+
+// sync menu of official account with definition kept in version control:
+// plan compares definition with live menu in a readable diff,
+// apply changes live menu as planned, if not changed by others since planned.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/MenInBack/weshin/base"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// ErrMenuChanged is returned by Apply if live menu changed since planned.
+var ErrMenuChanged = errors.New("menusync: live menu changed since planned, plan again")
+
+// LoadConfig parses menu definition in the format of menu/get response,
+// with unmarshal, or json.Unmarshal if nil.
+// YAML definition could be parsed by unmarshalers honoring json tags,
+// such as Unmarshal of github.com/ghodss/yaml.
+func LoadConfig(data []byte, unmarshal func([]byte, interface{}) error) (*base.MenuInfo, error) {
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+
+	config := new(base.MenuInfo)
+	err := unmarshal(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = base.ValidateButtons(config.Menu.Buttons)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range config.ConditionalMenu {
+		if m.MatchRule == nil || *m.MatchRule == (base.MatchRule{}) {
+			return nil, wx.ParameterError{InvalidParameter: "conditionalmenu.matchrule"}
+		}
+		err = base.ValidateButtons(m.Buttons)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// Syncer syncs menu of MP.
+type Syncer struct {
+	MP      *base.MP
+	Timeout int
+}
+
+// Plan changes to make live menu as config.
+func (s *Syncer) Plan(config *base.MenuInfo) (*Plan, error) {
+	live, err := s.MP.GetMenu(s.Timeout)
+	if err != nil {
+		if e, ok := err.(*wx.WechatError); !ok || e.ErrCode != errCodeMenuNotExist {
+			return nil, err
+		}
+		live = new(base.MenuInfo) // no menu yet
+	}
+
+	return diff(live, config), nil
+}
+
+// Apply plan to live menu, ErrMenuChanged if live menu changed since planned.
+func (s *Syncer) Apply(p *Plan) error {
+	if p.Empty() {
+		return nil
+	}
+
+	current, err := s.Plan(p.config)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(marshal(current.live), marshal(p.live)) {
+		return ErrMenuChanged
+	}
+
+	if p.Default != nil {
+		err = s.MP.CreateMenu(p.Default.Menu.Buttons, s.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	// delete stale conditional menus before adding new ones
+	for _, c := range p.Conditional {
+		if c.Action == ActionCreate {
+			continue
+		}
+		err = s.MP.DeleteConditionalMenu(c.MenuID, s.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range p.Conditional {
+		if c.Action == ActionDelete {
+			continue
+		}
+		_, err = s.MP.AddConditionalMenu(c.Menu, s.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// menu/get returns 46003 if no menu created
+const errCodeMenuNotExist = 46003
+
+func marshal(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -0,0 +1,106 @@
+package menusync
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/MenInBack/weshin/base"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// fakeMenuAPI serves menu api in place of wechat, replying menu/get with live.
+type fakeMenuAPI struct {
+	live     string
+	requests []string // path and body of requests other than menu/get
+}
+
+func (api *fakeMenuAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	reply := `{"errcode":0,"errmsg":"ok"}`
+	switch req.URL.Path {
+	case "/cgi-bin/menu/get":
+		reply = api.live
+	case "/cgi-bin/menu/addconditional":
+		reply = `{"menuid":"208396940"}`
+		fallthrough
+	default:
+		api.requests = append(api.requests, req.URL.Path+" "+string(body))
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewBufferString(reply)),
+		Request:    req,
+	}, nil
+}
+
+type testStorage struct{}
+
+func (testStorage) SetAccessToken(token string, expiresIn int64) {}
+func (testStorage) GetAccessToken() string                       { return "ACCESS_TOKEN" }
+func (testStorage) SetJSTicket(ticket *wx.APITicket)             {}
+func (testStorage) GetJSTicket(appID string) *wx.APITicket       { return nil }
+
+func serveFakeMenuAPI(live string) (api *fakeMenuAPI, stop func()) {
+	api = &fakeMenuAPI{live: live}
+	transport := http.DefaultTransport
+	http.DefaultTransport = api
+	return api, func() { http.DefaultTransport = transport }
+}
+
+func TestApply(t *testing.T) {
+	api, stop := serveFakeMenuAPI(liveMenu)
+	defer stop()
+
+	desired, err := LoadConfig([]byte(desiredMenu), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Syncer{MP: &base.MP{AppID: "wxappid", Storage: testStorage{}}}
+	p, err := s.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Apply(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.requests) != 3 ||
+		!strings.HasPrefix(api.requests[0], "/cgi-bin/menu/create ") ||
+		!strings.Contains(api.requests[0], "V1002_TODAY_MUSIC") ||
+		api.requests[1] != `/cgi-bin/menu/delconditional {"menuid":"208396939"}` ||
+		!strings.HasPrefix(api.requests[2], "/cgi-bin/menu/addconditional ") ||
+		!strings.Contains(api.requests[2], `"tag_id":"4"`) {
+		t.Errorf("unexpected requests: %q", api.requests)
+	}
+
+	// nothing applied if live menu changed since planned
+	api.requests = nil
+	api.live = strings.Replace(liveMenu, "208396939", "208396941", 1)
+	if err = s.Apply(p); err != ErrMenuChanged {
+		t.Error("expect ErrMenuChanged, got ", err)
+	}
+	if len(api.requests) != 0 {
+		t.Errorf("unexpected requests: %q", api.requests)
+	}
+
+	// default menu created if no menu yet
+	api.live = `{"errcode":46003,"errmsg":"menu no exist"}`
+	p, err = s.Plan(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Default == nil || p.Default.Action != ActionCreate || len(p.Conditional) != 2 {
+		t.Fatalf("unexpected plan:\n%s", p)
+	}
+	if err = s.Apply(p); err != nil || len(api.requests) != 3 {
+		t.Errorf("unexpected requests: %q, error %v", api.requests, err)
+	}
+}
@@ -0,0 +1,178 @@
+package message
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const defaultAllowListRefresh = time.Hour
+
+// IPAllowList rejects requests from ips not in the list of wechat callback ips,
+// which is fetched at the first request and refreshed in background.
+// The last list fetched is kept if refreshing failed.
+// Requests arriving while the list is fetched at first wait for the same fetch.
+type IPAllowList struct {
+	// Fetch returns ips or cidrs of wechat callback servers,
+	// such as (*base.MP).GetCallbackIP.
+	Fetch   func() ([]string, error)
+	Refresh time.Duration // an hour if zero
+	// TrustProxy takes client ip from X-Real-IP or the last ip of X-Forwarded-For,
+	// set only if server is behind a proxy setting them.
+	TrustProxy bool
+	// Errors receives errors of refreshing in background if set,
+	// errors are dropped if nobody is receiving. Server.Start sets it to errors of server if nil.
+	Errors chan error
+
+	mu         sync.RWMutex
+	nets       []*net.IPNet
+	fetchedAt  time.Time
+	refreshing bool
+	flight     wx.Flight
+
+	now func() time.Time // time.Now if nil
+}
+
+// Allowed tells whether request comes from wechat.
+func (l *IPAllowList) Allowed(req *http.Request) (bool, error) {
+	ip := net.ParseIP(l.clientIP(req))
+	if ip == nil {
+		return false, errors.New("allow list: invalid client ip of " + req.RemoteAddr)
+	}
+
+	nets, err := l.list()
+	if err != nil {
+		return false, err
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Handler rejects requests not allowed with 403 before next.
+func (l *IPAllowList) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ok, _ := l.Allowed(req)
+		if !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (l *IPAllowList) clientIP(req *http.Request) string {
+	if l.TrustProxy {
+		if ip := strings.TrimSpace(req.Header.Get("X-Real-IP")); len(ip) > 0 {
+			return ip
+		}
+		if xff := req.Header.Get("X-Forwarded-For"); len(xff) > 0 {
+			ips := strings.Split(xff, ",")
+			return strings.TrimSpace(ips[len(ips)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// list returns ip nets fetched, fetching at once if none,
+// or refreshing in background if stale.
+func (l *IPAllowList) list() ([]*net.IPNet, error) {
+	l.mu.RLock()
+	nets, fetchedAt, refreshing := l.nets, l.fetchedAt, l.refreshing
+	l.mu.RUnlock()
+
+	if nets == nil {
+		v, err := l.flight.Do("", func() (interface{}, error) {
+			return l.refresh()
+		})
+		nets, _ = v.([]*net.IPNet)
+		return nets, err
+	}
+	if !refreshing && l.currentTime().Sub(fetchedAt) > l.refreshInterval() {
+		l.mu.Lock()
+		if !l.refreshing {
+			l.refreshing = true
+			go func() {
+				if _, err := l.refresh(); err != nil {
+					l.notifyError(err)
+				}
+			}()
+		}
+		l.mu.Unlock()
+	}
+	return nets, nil
+}
+
+// refresh fetches the list, keeping the last list if failed.
+func (l *IPAllowList) refresh() ([]*net.IPNet, error) {
+	ips, err := l.Fetch()
+	var nets []*net.IPNet
+	if err == nil {
+		nets, err = parseIPNets(ips)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refreshing = false
+	if err != nil {
+		return l.nets, err
+	}
+	l.nets = nets
+	l.fetchedAt = l.currentTime()
+	return nets, nil
+}
+
+func (l *IPAllowList) notifyError(err error) {
+	if l.Errors == nil {
+		return
+	}
+	select {
+	case l.Errors <- wx.NotifyError{Err: err}:
+	default:
+	}
+}
+
+func (l *IPAllowList) currentTime() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+func (l *IPAllowList) refreshInterval() time.Duration {
+	if l.Refresh > 0 {
+		return l.Refresh
+	}
+	return defaultAllowListRefresh
+}
+
+func parseIPNets(ips []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(ips))
+	for _, s := range ips {
+		if !strings.Contains(s, "/") {
+			if strings.Contains(s, ":") {
+				s += "/128"
+			} else {
+				s += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
@@ -0,0 +1,132 @@
+package message
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+func TestIPAllowList(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	fetched := 0
+	var fail bool
+	l := &IPAllowList{
+		Fetch: func() ([]string, error) {
+			fetched++
+			if fail {
+				return nil, errors.New("fetch failed")
+			}
+			return []string{"101.226.103.0/25", "180.163.15.160"}, nil
+		},
+		Errors: make(chan error, 1),
+		now:    func() time.Time { return now },
+	}
+	s := &Server{Token: token, AllowList: l}
+
+	request := func(remote, forwarded string) int {
+		sig := "signature=invalid"
+		req := httptest.NewRequest("POST", "/?"+sig, strings.NewReader(textXML))
+		req.RemoteAddr = remote + ":443"
+		if len(forwarded) > 0 {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// allowed requests fail at signature check
+	for _, c := range []struct {
+		remote, forwarded string
+		code              int
+	}{
+		{"101.226.103.1", "", 400},
+		{"180.163.15.160", "", 400},
+		{"101.226.103.200", "", 403},
+		{"10.0.0.1", "1.2.3.4, 180.163.15.160", 403}, // proxy not trusted
+	} {
+		if code := request(c.remote, c.forwarded); code != c.code {
+			t.Errorf("%s %s: code %d, expect %d", c.remote, c.forwarded, code, c.code)
+		}
+	}
+
+	l.TrustProxy = true
+	if code := request("10.0.0.1", "1.2.3.4, 180.163.15.160"); code != 400 {
+		t.Error("expect forwarded ip allowed, got ", code)
+	}
+	if code := request("10.0.0.1", "180.163.15.160, 1.2.3.4"); code != 403 {
+		t.Error("expect spoofed forwarded ip rejected, got ", code)
+	}
+
+	// not refreshed till stale
+	now = now.Add(defaultAllowListRefresh)
+	request("101.226.103.1", "")
+	if fetched != 1 {
+		t.Errorf("fetched %d times before stale", fetched)
+	}
+
+	// stale list kept if refreshing failed
+	fail = true
+	now = now.Add(time.Second)
+	if code := request("101.226.103.1", ""); code != 400 {
+		t.Error("expect stale list used, got ", code)
+	}
+	select {
+	case err := <-l.Errors:
+		if _, ok := err.(wx.NotifyError); !ok {
+			t.Error("unexpected error: ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect error of refreshing")
+	}
+	l.mu.RLock()
+	n, refreshing := len(l.nets), l.refreshing
+	l.mu.RUnlock()
+	if fetched != 2 || n != 2 || refreshing {
+		t.Errorf("fetched %d times, %d nets kept, refreshing %v", fetched, n, refreshing)
+	}
+
+	fail = false
+	now = now.Add(time.Second)
+	if _, err := l.refresh(); err != nil || !l.fetchedAt.Equal(now) {
+		t.Errorf("refreshed at %v, error %v", l.fetchedAt, err)
+	}
+}
+
+func TestIPAllowListFetchOnce(t *testing.T) {
+	var fetched int32
+	release := make(chan struct{})
+	l := &IPAllowList{
+		Fetch: func() ([]string, error) {
+			atomic.AddInt32(&fetched, 1)
+			<-release
+			return []string{"180.163.15.160"}, nil
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/", nil)
+			req.RemoteAddr = "180.163.15.160:443"
+			if ok, err := l.Allowed(req); !ok || err != nil {
+				t.Errorf("allowed %v, error %v", ok, err)
+			}
+		}()
+	}
+	// requests arriving later use the list fetched, so it is fetched once however they interleave
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if fetched != 1 {
+		t.Errorf("fetched %d times, expect 1", fetched)
+	}
+}
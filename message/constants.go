@@ -0,0 +1,43 @@
+package message
+
+// message type
+const (
+	TypeText       = "text"
+	TypeImage      = "image"
+	TypeVoice      = "voice"
+	TypeVideo      = "video"
+	TypeShortVideo = "shortvideo"
+	TypeLocation   = "location"
+	TypeLink       = "link"
+	TypeEvent      = "event"
+
+	// types of customer service message only
+	TypeMusic           = "music"
+	TypeNews            = "news"
+	TypeMPNews          = "mpnews"
+	TypeMsgMenu         = "msgmenu"
+	TypeMiniProgramPage = "miniprogrampage"
+)
+
+// event type
+const (
+	EventSubscribe   = "subscribe"
+	EventUnsubscribe = "unsubscribe"
+	EventScan        = "SCAN"
+	EventLocation    = "LOCATION"
+	EventClick       = "CLICK"
+	EventView        = "VIEW"
+
+	EventTemplateSendJobFinish = "TEMPLATESENDJOBFINISH"
+	EventMassSendJobFinish     = "MASSSENDJOBFINISH"
+	EventPublishJobFinish      = "PUBLISHJOBFINISH"
+)
+
+// encrypt type in query of message push
+const (
+	EncryptTypeRaw = "raw"
+	EncryptTypeAES = "aes"
+)
+
+// replyEmpty tells wechat that message is handled without reply.
+const replyEmpty = "success"
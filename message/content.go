@@ -1,15 +1,24 @@
 package message
 
+import (
+	"context"
+	"encoding/xml"
+	"reflect"
+	"strings"
+)
+
 type Message struct {
 	Meta
 	Content
+
+	ctx context.Context
 }
 
 type Meta struct {
-	FromUserName string `xml:"FromUserName,cdata"`
-	ToUserName   string `xml:"ToUserName,cdata"`
-	CreateTime   int64  `xml:"CreateTime,cdata"`
-	MessageType  string `xml:"MsgType,cdata"`
+	FromUserName string `xml:"FromUserName"`
+	ToUserName   string `xml:"ToUserName"`
+	CreateTime   int64  `xml:"CreateTime"`
+	MessageType  string `xml:"MsgType"`
 }
 
 type Content interface {
@@ -17,8 +26,8 @@ type Content interface {
 }
 
 type Text struct {
-	Content   string `xml:"Content,cdata"`
-	MessageID int64  `xml:"MsgId,cdata"`
+	Content   string `xml:"Content"`
+	MessageID int64  `xml:"MsgId,omitempty"`
 }
 
 func (c *Text) GetMessageID() int64 {
@@ -27,3 +36,200 @@ func (c *Text) GetMessageID() int64 {
 	}
 	return c.MessageID
 }
+
+// Event pushed from wechat, events have no message id.
+type Event struct {
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey,omitempty"`
+	Ticket   string `xml:"Ticket,omitempty"`
+	MsgID    int64  `xml:"MsgID,omitempty"`  // of job finish events
+	Status   string `xml:"Status,omitempty"` // of job finish events
+
+	// of MASSSENDJOBFINISH event
+	TotalCount           int32                 `xml:"TotalCount,omitempty"`
+	FilterCount          int32                 `xml:"FilterCount,omitempty"`
+	SentCount            int32                 `xml:"SentCount,omitempty"`
+	ErrorCount           int32                 `xml:"ErrorCount,omitempty"`
+	CopyrightCheckResult *CopyrightCheckResult `xml:"CopyrightCheckResult,omitempty"`
+	ArticleURLResult     *ArticleURLResult     `xml:"ArticleUrlResult,omitempty"`
+
+	// of PUBLISHJOBFINISH event
+	PublishEventInfo *PublishEventInfo `xml:"PublishEventInfo,omitempty"`
+}
+
+func (c *Event) GetMessageID() int64 {
+	return 0
+}
+
+// qrScenePrefix of EventKey in subscribe event by scanning qrcode.
+const qrScenePrefix = "qrscene_"
+
+// QRScene returns scene of qrcode scanned, in subscribe event of user not following,
+// or SCAN event of follower. Scene id is returned in decimal string.
+func (c *Event) QRScene() (scene string, ok bool) {
+	switch c.Event {
+	case EventSubscribe:
+		if strings.HasPrefix(c.EventKey, qrScenePrefix) {
+			return strings.TrimPrefix(c.EventKey, qrScenePrefix), true
+		}
+	case EventScan:
+		if len(c.EventKey) > 0 {
+			return c.EventKey, true
+		}
+	}
+	return "", false
+}
+
+// Raw holds messages of types not parsed yet.
+type Raw struct {
+	MessageID int64  `xml:"MsgId"`
+	InnerXML  []byte `xml:",innerxml"`
+}
+
+func (c *Raw) GetMessageID() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.MessageID
+}
+
+// Unmarshal decrypted xml message from wechat.
+func Unmarshal(data []byte) (*Message, error) {
+	msg := new(Message)
+	err := xml.Unmarshal(data, &msg.Meta)
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.MessageType {
+	case TypeText:
+		msg.Content = new(Text)
+	case TypeEvent:
+		msg.Content = new(Event)
+	default:
+		msg.Content = new(Raw)
+	}
+
+	err = xml.Unmarshal(data, msg.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// Marshal reply message into xml.
+func Marshal(msg *Message) ([]byte, error) {
+	return xml.Marshal(msg)
+}
+
+// MarshalXML writes meta and content fields side by side under <xml>,
+// with strings wrapped in CDATA.
+func (m Message) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Local: "xml"}}
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	err = encodeFields(e, reflect.ValueOf(m.Meta))
+	if err != nil {
+		return err
+	}
+	if m.Content != nil {
+		err = encodeFields(e, reflect.ValueOf(m.Content))
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+type cdata struct {
+	Data string `xml:",cdata"`
+}
+
+func encodeFields(e *xml.Encoder, val reflect.Value) error {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	typ := val.Type()
+
+	for i := 0; i < val.NumField(); i++ {
+		f := typ.Field(i)
+		v := val.Field(i)
+
+		if f.PkgPath != "" || f.Name == "XMLName" {
+			continue // unexported
+		}
+
+		tags := strings.Split(f.Tag.Get("xml"), ",")
+		name := tags[0]
+		if name == "-" || strings.Contains(f.Tag.Get("xml"), ",innerxml") {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if err := encodeFields(e, v); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if len(tags) > 1 && tags[1] == "omitempty" && isEmptyValue(v) {
+			continue
+		}
+
+		// parent>child wraps value in parent
+		var parent *xml.StartElement
+		if i := strings.Index(name, ">"); i >= 0 {
+			parent = &xml.StartElement{Name: xml.Name{Local: name[:i]}}
+			name = name[i+1:]
+			if err := e.EncodeToken(*parent); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		start := xml.StartElement{Name: xml.Name{Local: name}}
+		if v.Kind() == reflect.String {
+			err = e.EncodeElement(cdata{v.String()}, start)
+		} else {
+			err = e.EncodeElement(v.Interface(), start)
+		}
+		if err != nil {
+			return err
+		}
+
+		if parent != nil {
+			if err := e.EncodeToken(parent.End()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package message
+
+import (
+	"testing"
+)
+
+func TestUnmarshalMassSendJobFinish(t *testing.T) {
+	data := `<xml><ToUserName><![CDATA[gh_4d00ed8d6399]]></ToUserName><FromUserName><![CDATA[oV5CrjpxgaGXNHIQigzNlgLTnwic]]></FromUserName><CreateTime>1481013459</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[MASSSENDJOBFINISH]]></Event><MsgID>1000001625</MsgID><Status><![CDATA[err(30003)]]></Status><TotalCount>0</TotalCount><FilterCount>0</FilterCount><SentCount>0</SentCount><ErrorCount>0</ErrorCount>` +
+		`<CopyrightCheckResult><Count>2</Count><ResultList>` +
+		`<item><ArticleIdx>1</ArticleIdx><UserDeclareState>0</UserDeclareState><AuditState>2</AuditState><OriginalArticleUrl><![CDATA[Url_1]]></OriginalArticleUrl><OriginalArticleType>1</OriginalArticleType><CanReprint>1</CanReprint><NeedReplaceContent>1</NeedReplaceContent><NeedShowReprintSource>1</NeedShowReprintSource></item>` +
+		`<item><ArticleIdx>2</ArticleIdx><UserDeclareState>0</UserDeclareState><AuditState>2</AuditState><OriginalArticleUrl><![CDATA[Url_2]]></OriginalArticleUrl><OriginalArticleType>1</OriginalArticleType><CanReprint>1</CanReprint><NeedReplaceContent>1</NeedReplaceContent><NeedShowReprintSource>1</NeedShowReprintSource></item>` +
+		`</ResultList><CheckState>2</CheckState></CopyrightCheckResult>` +
+		`<ArticleUrlResult><Count>1</Count><ResultList><item><ArticleIdx>1</ArticleIdx><ArticleUrl><![CDATA[Url]]></ArticleUrl></item></ResultList></ArticleUrlResult></xml>`
+
+	msg, err := Unmarshal([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := msg.Content.(*Event)
+	if !ok || e.Event != EventMassSendJobFinish {
+		t.Fatalf("unexpected content: %+v", msg.Content)
+	}
+	if e.MsgID != 1000001625 || e.Status != "err(30003)" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	r := e.CopyrightCheckResult
+	if r == nil || r.Count != 2 || r.CheckState != 2 || len(r.ResultList) != 2 || r.ResultList[1].OriginalArticleURL != "Url_2" {
+		t.Errorf("unexpected copyright check result: %+v", r)
+	}
+	if u := e.ArticleURLResult; u == nil || len(u.ResultList) != 1 || u.ResultList[0].ArticleURL != "Url" {
+		t.Errorf("unexpected article url result: %+v", u)
+	}
+}
+
+func TestEventQRScene(t *testing.T) {
+	for i, c := range []struct {
+		event Event
+		scene string
+		ok    bool
+	}{
+		{Event{Event: EventSubscribe, EventKey: "qrscene_123123", Ticket: "TICKET"}, "123123", true},
+		{Event{Event: EventSubscribe, EventKey: "qrscene_campaign_2018"}, "campaign_2018", true},
+		{Event{Event: EventSubscribe}, "", false},
+		{Event{Event: EventScan, EventKey: "123123", Ticket: "TICKET"}, "123123", true},
+		{Event{Event: EventClick, EventKey: "qrscene_123"}, "", false},
+	} {
+		scene, ok := c.event.QRScene()
+		if scene != c.scene || ok != c.ok {
+			t.Errorf("case %d: scene %q %v, expect %q %v", i, scene, ok, c.scene, c.ok)
+		}
+	}
+}
+
+func TestUnmarshalPublishJobFinish(t *testing.T) {
+	data := `<xml><ToUserName><![CDATA[gh_4d00ed8d6399]]></ToUserName><FromUserName><![CDATA[oV5CrjpxgaGXNHIQigzNlgLTnwic]]></FromUserName><CreateTime>1481013459</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[PUBLISHJOBFINISH]]></Event>` +
+		`<PublishEventInfo><publish_id>2247503051</publish_id><publish_status>2</publish_status><article_id><![CDATA[b5O2OUs25HBxRceL7hfReg-U9QGeq9zQjiDvy WP4Hq4]]></article_id>` +
+		`<article_detail><count>1</count><item><idx>1</idx><article_url><![CDATA[ARTICLE_URL]]></article_url></item></article_detail>` +
+		`<fail_idx>1</fail_idx><fail_idx>2</fail_idx></PublishEventInfo></xml>`
+
+	msg, err := Unmarshal([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, ok := msg.Content.(*Event)
+	if !ok || e.Event != EventPublishJobFinish || e.PublishEventInfo == nil {
+		t.Fatalf("unexpected content: %+v", msg.Content)
+	}
+	info := e.PublishEventInfo
+	if info.PublishID != "2247503051" || info.PublishStatus != PublishOriginalFail || len(info.FailIdx) != 2 || info.FailIdx[1] != 2 {
+		t.Errorf("unexpected publish info: %+v", info)
+	}
+	if d := info.ArticleDetail; d == nil || d.Count != 1 || len(d.Item) != 1 || d.Item[0].ArticleURL != "ARTICLE_URL" {
+		t.Errorf("unexpected article detail: %+v", d)
+	}
+}
+
+func TestMarshalNewsReply(t *testing.T) {
+	msg := &Message{
+		Meta: Meta{ToUserName: "openid", FromUserName: "gh_id", CreateTime: 1409735668},
+		Content: &NewsReply{Articles: []ReplyArticle{
+			{Title: "title1", URL: "url1"},
+			{Title: "title2", URL: "url2"},
+		}},
+	}
+	completeReply(&Message{}, msg)
+
+	data, err := Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `<xml><FromUserName><![CDATA[gh_id]]></FromUserName><ToUserName><![CDATA[openid]]></ToUserName><CreateTime>1409735668</CreateTime><MsgType><![CDATA[news]]></MsgType><ArticleCount>2</ArticleCount><Articles>` +
+		`<item><Title>title1</Title><Description></Description><PicUrl></PicUrl><Url>url1</Url></item>` +
+		`<item><Title>title2</Title><Description></Description><PicUrl></PicUrl><Url>url2</Url></item></Articles></xml>`
+	if string(data) != expect {
+		t.Error("unexpected xml: ", string(data))
+	}
+}
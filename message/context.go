@@ -0,0 +1,42 @@
+package message
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	authorizerAppIDKey contextKey = iota
+	sessionKey
+)
+
+// Context of message, never nil.
+func (m *Message) Context() context.Context {
+	if m.ctx != nil {
+		return m.ctx
+	}
+	return context.Background()
+}
+
+// WithContext returns a shallow copy of message with ctx.
+func (m *Message) WithContext(ctx context.Context) *Message {
+	msg := *m
+	msg.ctx = ctx
+	return &msg
+}
+
+// WithAuthorizerAppID marks messages received for authorizer in component mode.
+func WithAuthorizerAppID(ctx context.Context, appID string) context.Context {
+	return context.WithValue(ctx, authorizerAppIDKey, appID)
+}
+
+// AuthorizerAppID of message received in component mode, empty otherwise.
+func AuthorizerAppID(ctx context.Context) string {
+	appID, _ := ctx.Value(authorizerAppIDKey).(string)
+	return appID
+}
+
+func contextWithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey, s)
+}
@@ -0,0 +1,274 @@
+package message
+
+// customer service message
+// https://mp.weixin.qq.com/wiki?t=resource/res_main&id=mp1421140547
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	customSendURI   = "https://api.weixin.qq.com/cgi-bin/message/custom/send"
+	customTypingURI = "https://api.weixin.qq.com/cgi-bin/message/custom/typing"
+)
+
+// limits of customer service message
+const (
+	MaxCustomArticles = 1 // news of customer service message holds 1 article only
+	MaxMenuItems      = 10
+)
+
+// CustomMessage sent by customer service api, with content of MessageType set.
+type CustomMessage struct {
+	ToUser          string                 `json:"touser"`
+	MessageType     string                 `json:"msgtype"`
+	Text            *CustomText            `json:"text,omitempty"`
+	Image           *CustomMedia           `json:"image,omitempty"`
+	Voice           *CustomMedia           `json:"voice,omitempty"`
+	Video           *CustomVideo           `json:"video,omitempty"`
+	Music           *CustomMusic           `json:"music,omitempty"`
+	News            *CustomNews            `json:"news,omitempty"`
+	MPNews          *CustomMedia           `json:"mpnews,omitempty"`
+	MsgMenu         *CustomMsgMenu         `json:"msgmenu,omitempty"`
+	MiniProgramPage *CustomMiniProgramPage `json:"miniprogrampage,omitempty"`
+	CustomService   *CustomService         `json:"customservice,omitempty"` // to send as kf account
+}
+
+type CustomText struct {
+	Content string `json:"content"`
+}
+
+type CustomMedia struct {
+	MediaID string `json:"media_id"`
+}
+
+type CustomVideo struct {
+	MediaID      string `json:"media_id"`
+	ThumbMediaID string `json:"thumb_media_id"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type CustomMusic struct {
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	MusicURL     string `json:"musicurl"`
+	HQMusicURL   string `json:"hqmusicurl"`
+	ThumbMediaID string `json:"thumb_media_id"`
+}
+
+type CustomNews struct {
+	Articles []CustomArticle `json:"articles"`
+}
+
+type CustomArticle struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	PicURL      string `json:"picurl"`
+}
+
+// CustomMsgMenu is a menu message, click on item sends content of item with bizmsgmenuid to server.
+type CustomMsgMenu struct {
+	HeadContent string           `json:"head_content"`
+	List        []CustomMenuItem `json:"list"`
+	TailContent string           `json:"tail_content"`
+}
+
+type CustomMenuItem struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+type CustomMiniProgramPage struct {
+	Title        string `json:"title"`
+	AppID        string `json:"appid"`
+	PagePath     string `json:"pagepath"`
+	ThumbMediaID string `json:"thumb_media_id"`
+}
+
+type CustomService struct {
+	KFAccount string `json:"kf_account"`
+}
+
+// NewCustomText to user.
+func NewCustomText(toUser, content string) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeText, Text: &CustomText{content}}
+}
+
+// NewCustomImage to user with media id of image uploaded.
+func NewCustomImage(toUser, mediaID string) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeImage, Image: &CustomMedia{mediaID}}
+}
+
+// NewCustomVoice to user with media id of voice uploaded.
+func NewCustomVoice(toUser, mediaID string) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeVoice, Voice: &CustomMedia{mediaID}}
+}
+
+// NewCustomVideo to user.
+func NewCustomVideo(toUser string, video *CustomVideo) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeVideo, Video: video}
+}
+
+// NewCustomMusic to user.
+func NewCustomMusic(toUser string, music *CustomMusic) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeMusic, Music: music}
+}
+
+// NewCustomNews to user, linking to outer page.
+func NewCustomNews(toUser string, article CustomArticle) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeNews, News: &CustomNews{[]CustomArticle{article}}}
+}
+
+// NewCustomMPNews to user with media id of news material.
+func NewCustomMPNews(toUser, mediaID string) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeMPNews, MPNews: &CustomMedia{mediaID}}
+}
+
+// NewCustomMsgMenu to user.
+func NewCustomMsgMenu(toUser string, menu *CustomMsgMenu) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeMsgMenu, MsgMenu: menu}
+}
+
+// NewCustomMiniProgramPage to user, miniprogram should be linked to official account.
+func NewCustomMiniProgramPage(toUser string, page *CustomMiniProgramPage) *CustomMessage {
+	return &CustomMessage{ToUser: toUser, MessageType: TypeMiniProgramPage, MiniProgramPage: page}
+}
+
+// As kf account to send message.
+func (m *CustomMessage) As(kfAccount string) *CustomMessage {
+	m.CustomService = &CustomService{kfAccount}
+	return m
+}
+
+// Validate message before sending, content of MessageType should be set.
+func (m *CustomMessage) Validate() error {
+	if len(m.ToUser) <= 0 {
+		return wx.ParameterError{InvalidParameter: "toUser"}
+	}
+
+	var ok bool
+	switch m.MessageType {
+	case TypeText:
+		ok = m.Text != nil && len(m.Text.Content) > 0
+	case TypeImage:
+		ok = m.Image != nil && len(m.Image.MediaID) > 0
+	case TypeVoice:
+		ok = m.Voice != nil && len(m.Voice.MediaID) > 0
+	case TypeVideo:
+		ok = m.Video != nil && len(m.Video.MediaID) > 0 && len(m.Video.ThumbMediaID) > 0
+	case TypeMusic:
+		ok = m.Music != nil && len(m.Music.MusicURL) > 0 && len(m.Music.ThumbMediaID) > 0
+	case TypeNews:
+		ok = m.News != nil && len(m.News.Articles) > 0 && len(m.News.Articles) <= MaxCustomArticles
+	case TypeMPNews:
+		ok = m.MPNews != nil && len(m.MPNews.MediaID) > 0
+	case TypeMsgMenu:
+		ok = m.MsgMenu != nil && len(m.MsgMenu.List) > 0 && len(m.MsgMenu.List) <= MaxMenuItems
+	case TypeMiniProgramPage:
+		ok = m.MiniProgramPage != nil && len(m.MiniProgramPage.AppID) > 0 && len(m.MiniProgramPage.ThumbMediaID) > 0
+	default:
+		return wx.ParameterError{InvalidParameter: "msgtype: " + m.MessageType}
+	}
+	if !ok {
+		return wx.ParameterError{InvalidParameter: m.MessageType}
+	}
+	return nil
+}
+
+// NewCustomMessage converts a passive reply into customer service message,
+// video reply is converted only with ThumbMediaID,
+// news reply is converted only if articles fit in a customer service message.
+func NewCustomMessage(reply *Message) (*CustomMessage, error) {
+	toUser := reply.ToUserName
+
+	switch c := reply.Content.(type) {
+	case *Text:
+		return NewCustomText(toUser, c.Content), nil
+	case *ImageReply:
+		return NewCustomImage(toUser, c.Image.MediaID), nil
+	case *VoiceReply:
+		return NewCustomVoice(toUser, c.Voice.MediaID), nil
+	case *VideoReply:
+		if len(c.Video.ThumbMediaID) <= 0 {
+			return nil, wx.ParameterError{InvalidParameter: "reply video: no ThumbMediaID"}
+		}
+		return NewCustomVideo(toUser, &CustomVideo{
+			MediaID:      c.Video.MediaID,
+			ThumbMediaID: c.Video.ThumbMediaID,
+			Title:        c.Video.Title,
+			Description:  c.Video.Description,
+		}), nil
+	case *MusicReply:
+		return NewCustomMusic(toUser, &CustomMusic{
+			Title:        c.Music.Title,
+			Description:  c.Music.Description,
+			MusicURL:     c.Music.MusicURL,
+			HQMusicURL:   c.Music.HQMusicURL,
+			ThumbMediaID: c.Music.ThumbMediaID,
+		}), nil
+	case *NewsReply:
+		if len(c.Articles) != MaxCustomArticles {
+			return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("reply articles: %d", len(c.Articles))}
+		}
+		a := c.Articles[0]
+		return NewCustomNews(toUser, CustomArticle{
+			Title:       a.Title,
+			Description: a.Description,
+			URL:         a.URL,
+			PicURL:      a.PicURL,
+		}), nil
+	}
+
+	return nil, wx.ParameterError{InvalidParameter: fmt.Sprintf("reply content %T", reply.Content)}
+}
+
+// SendCustomMessage to user who sent message in 48 hours.
+// https://api.weixin.qq.com/cgi-bin/message/custom/send?access_token=ACCESS_TOKEN
+func SendCustomMessage(accessToken string, msg *CustomMessage, timeout int) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	return postCustom(accessToken, customSendURI, msg, timeout)
+}
+
+// SendCustomTyping shows or cancels typing status to user.
+// https://api.weixin.qq.com/cgi-bin/message/custom/typing?access_token=ACCESS_TOKEN
+func SendCustomTyping(accessToken, toUser string, typing bool, timeout int) error {
+	if len(toUser) <= 0 {
+		return wx.ParameterError{InvalidParameter: "toUser"}
+	}
+
+	body := struct {
+		ToUser  string `json:"touser"`
+		Command string `json:"command"`
+	}{toUser, "CancelTyping"}
+	if typing {
+		body.Command = "Typing"
+	}
+
+	return postCustom(accessToken, customTypingURI, body, timeout)
+}
+
+func postCustom(accessToken, path string, msg interface{}, timeout int) error {
+	req := wx.HttpClient{
+		Path:        path,
+		ContentType: "application/json",
+		Parameters: []wx.QueryParameter{
+			{Key: "access_token", Value: accessToken},
+		},
+		Timeout: timeout,
+	}
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %s", err)
+	}
+
+	return req.DoPost(bytes.NewBuffer(b), nil)
+}
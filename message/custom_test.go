@@ -0,0 +1,90 @@
+package message
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewCustomMessage(t *testing.T) {
+	meta := Meta{ToUserName: "openid"}
+	for _, c := range []struct {
+		content Content
+		expect  CustomMessage
+	}{
+		{&Text{Content: "hello"}, CustomMessage{MessageType: TypeText, Text: &CustomText{"hello"}}},
+		{&ImageReply{ReplyMedia{"image"}}, CustomMessage{MessageType: TypeImage, Image: &CustomMedia{"image"}}},
+		{&VoiceReply{ReplyMedia{"voice"}}, CustomMessage{MessageType: TypeVoice, Voice: &CustomMedia{"voice"}}},
+		{
+			&VideoReply{ReplyVideo{MediaID: "video", Title: "title", ThumbMediaID: "thumb"}},
+			CustomMessage{MessageType: TypeVideo, Video: &CustomVideo{MediaID: "video", ThumbMediaID: "thumb", Title: "title"}},
+		},
+		{
+			&MusicReply{ReplyMusic{MusicURL: "url", ThumbMediaID: "thumb"}},
+			CustomMessage{MessageType: TypeMusic, Music: &CustomMusic{MusicURL: "url", ThumbMediaID: "thumb"}},
+		},
+		{
+			&NewsReply{Articles: []ReplyArticle{{Title: "title", URL: "url"}}},
+			CustomMessage{MessageType: TypeNews, News: &CustomNews{[]CustomArticle{{Title: "title", URL: "url"}}}},
+		},
+	} {
+		msg, err := NewCustomMessage(&Message{Meta: meta, Content: c.content})
+		if err != nil {
+			t.Errorf("%T: %s", c.content, err)
+			continue
+		}
+		c.expect.ToUser = "openid"
+		if !reflect.DeepEqual(*msg, c.expect) {
+			t.Errorf("%T: converted into %+v", c.content, msg)
+		}
+		if err = msg.Validate(); err != nil {
+			t.Errorf("%T: %s", c.content, err)
+		}
+	}
+
+	for _, content := range []Content{
+		&VideoReply{ReplyVideo{MediaID: "video"}},
+		&NewsReply{Articles: make([]ReplyArticle, 2)},
+		&Event{Event: EventClick},
+	} {
+		if _, err := NewCustomMessage(&Message{Meta: meta, Content: content}); err == nil {
+			t.Errorf("%T: expect error", content)
+		}
+	}
+}
+
+func TestCustomMessage(t *testing.T) {
+	msg := NewCustomMsgMenu("OPENID", &CustomMsgMenu{
+		HeadContent: "您对本次服务是否满意呢? ",
+		List: []CustomMenuItem{
+			{ID: "101", Content: "满意"},
+			{ID: "102", Content: "不满意"},
+		},
+		TailContent: "欢迎再次光临",
+	}).As("test1@kftest")
+	if err := msg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(msg)
+	for _, s := range []string{`"msgtype":"msgmenu"`, `"customservice":{"kf_account":"test1@kftest"}`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("marshaled %s, expect %s", data, s)
+		}
+	}
+	if strings.Contains(string(data), `"text"`) {
+		t.Errorf("marshaled %s, expect no text", data)
+	}
+
+	for i, m := range []*CustomMessage{
+		NewCustomText("", "hello"),
+		NewCustomImage("OPENID", ""),
+		NewCustomVideo("OPENID", &CustomVideo{MediaID: "MEDIA_ID"}),
+		{ToUser: "OPENID", MessageType: TypeNews},
+		{ToUser: "OPENID", MessageType: "unknown"},
+	} {
+		if err := m.Validate(); err == nil {
+			t.Errorf("case %d: expect invalid", i)
+		}
+	}
+}
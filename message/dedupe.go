@@ -0,0 +1,126 @@
+package message
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MenInBack/weshin/wx"
+)
+
+// wechat retries a message 3 times within 15 seconds if not replied in 5 seconds.
+const defaultDedupeTTL = 60 * time.Second
+
+// ReplyStore caches replies of handled messages for Deduplicator.
+type ReplyStore interface {
+	// SetReply caches plain reply for message key, should expire after ttl,
+	// an empty reply means the message is handled without reply.
+	SetReply(key string, reply []byte, ttl time.Duration)
+	// GetReply returns the reply cached, ok is false if not found or expired.
+	GetReply(key string) (reply []byte, ok bool)
+}
+
+// Deduplicator runs handler once for retried messages, keyed by MsgId,
+// or by FromUserName and CreateTime for events.
+// Retries arriving while the first delivery is in process wait for its reply.
+type Deduplicator struct {
+	Store ReplyStore
+	TTL   time.Duration // defaultDedupeTTL if not set
+
+	flight wx.Flight
+}
+
+// Do returns reply cached for msg, or calls handle and caches its reply.
+func (d *Deduplicator) Do(msg *Message, handle func() ([]byte, error)) ([]byte, error) {
+	key := dedupeKey(msg)
+
+	v, err := d.flight.Do(key, func() (interface{}, error) {
+		if reply, ok := d.Store.GetReply(key); ok {
+			return reply, nil
+		}
+		reply, err := handle()
+		if err == nil {
+			d.Store.SetReply(key, reply, d.ttl())
+		}
+		return reply, err
+	})
+	reply, _ := v.([]byte)
+	return reply, err
+}
+
+func (d *Deduplicator) ttl() time.Duration {
+	if d.TTL > 0 {
+		return d.TTL
+	}
+	return defaultDedupeTTL
+}
+
+// ToUserName distinguishes official accounts sharing one store.
+func dedupeKey(msg *Message) string {
+	if msg.Content != nil {
+		if id := msg.GetMessageID(); id != 0 {
+			return msg.ToUserName + ":" + strconv.FormatInt(id, 10)
+		}
+	}
+	return msg.ToUserName + ":" + msg.FromUserName + ":" + strconv.FormatInt(msg.CreateTime, 10)
+}
+
+// MemoryReplyStore implements ReplyStore in process memory.
+type MemoryReplyStore struct {
+	cache memoryCache
+}
+
+func NewMemoryReplyStore() *MemoryReplyStore {
+	return new(MemoryReplyStore)
+}
+
+func (s *MemoryReplyStore) SetReply(key string, reply []byte, ttl time.Duration) {
+	s.cache.set(key, reply, ttl)
+}
+
+func (s *MemoryReplyStore) GetReply(key string) ([]byte, bool) {
+	return s.cache.get(key)
+}
+
+// memoryCache holds data in memory till expired.
+type memoryCache struct {
+	mu    sync.Mutex
+	items map[string]cachedItem
+}
+
+type cachedItem struct {
+	data     []byte
+	expireAt time.Time
+}
+
+func (c *memoryCache) set(key string, data []byte, ttl time.Duration) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items == nil {
+		c.items = make(map[string]cachedItem)
+	}
+	for k, item := range c.items {
+		if now.After(item.expireAt) {
+			delete(c.items, k)
+		}
+	}
+	c.items[key] = cachedItem{data, now.Add(ttl)}
+}
+
+func (c *memoryCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[key]
+	if !ok || time.Now().After(item.expireAt) {
+		return nil, false
+	}
+	return item.data, true
+}
+
+func (c *memoryCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, key)
+}
@@ -0,0 +1,34 @@
+package message
+
+// result of mass message reported in MASSSENDJOBFINISH event
+// https://mp.weixin.qq.com/wiki/ 消息管理/群发接口和原创校验
+
+// CopyrightCheckResult of articles in mass message.
+type CopyrightCheckResult struct {
+	Count      int32                  `xml:"Count"`
+	ResultList []CopyrightCheckDetail `xml:"ResultList>item"`
+	CheckState int32                  `xml:"CheckState"` // 1 passed, 2 rejected, 3 passed with reprint replaced
+}
+
+// CopyrightCheckDetail of article, ArticleIdx starts from 1.
+type CopyrightCheckDetail struct {
+	ArticleIdx            int32  `xml:"ArticleIdx"`
+	UserDeclareState      int32  `xml:"UserDeclareState"`
+	AuditState            int32  `xml:"AuditState"`
+	OriginalArticleURL    string `xml:"OriginalArticleUrl"`
+	OriginalArticleType   int32  `xml:"OriginalArticleType"`
+	CanReprint            int32  `xml:"CanReprint"`
+	NeedReplaceContent    int32  `xml:"NeedReplaceContent"`
+	NeedShowReprintSource int32  `xml:"NeedShowReprintSource"`
+}
+
+// ArticleURLResult holds urls of articles sent.
+type ArticleURLResult struct {
+	Count      int32        `xml:"Count"`
+	ResultList []ArticleURL `xml:"ResultList>item"`
+}
+
+type ArticleURL struct {
+	ArticleIdx int32  `xml:"ArticleIdx"`
+	ArticleURL string `xml:"ArticleUrl"`
+}
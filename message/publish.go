@@ -0,0 +1,35 @@
+package message
+
+// result of publishing reported in PUBLISHJOBFINISH event
+// https://mp.weixin.qq.com/wiki/ 发布能力/事件推送发布结果
+
+// status of publishing
+const (
+	PublishSuccess      = 0
+	PublishInProcess    = 1
+	PublishOriginalFail = 2
+	PublishFail         = 3
+	PublishAuditFail    = 4
+	PublishDeleted      = 5 // by user after success
+	PublishBanned       = 6 // by system after success
+)
+
+// PublishEventInfo of publishing job, FailIdx lists index of articles failed, starting from 1.
+type PublishEventInfo struct {
+	PublishID     string           `xml:"publish_id"`
+	PublishStatus int32            `xml:"publish_status"`
+	ArticleID     string           `xml:"article_id,omitempty"`
+	ArticleDetail *PublishArticles `xml:"article_detail,omitempty"`
+	FailIdx       []int32          `xml:"fail_idx,omitempty"`
+}
+
+// PublishArticles holds urls of articles published.
+type PublishArticles struct {
+	Count int32            `xml:"count"`
+	Item  []PublishArticle `xml:"item"`
+}
+
+type PublishArticle struct {
+	Idx        int32  `xml:"idx"`
+	ArticleURL string `xml:"article_url"`
+}
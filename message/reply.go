@@ -0,0 +1,87 @@
+package message
+
+// passive reply message
+// https://mp.weixin.qq.com/wiki?t=resource/res_main&id=mp1421140543
+
+// MaxReplyArticles of news reply.
+const MaxReplyArticles = 8
+
+// ImageReply with media id of image uploaded.
+type ImageReply struct {
+	Image ReplyMedia `xml:"Image"`
+}
+
+// VoiceReply with media id of voice uploaded.
+type VoiceReply struct {
+	Voice ReplyMedia `xml:"Voice"`
+}
+
+// VideoReply with media id of video uploaded.
+type VideoReply struct {
+	Video ReplyVideo `xml:"Video"`
+}
+
+// MusicReply with thumb media id uploaded.
+type MusicReply struct {
+	Music ReplyMusic `xml:"Music"`
+}
+
+// NewsReply of articles linking to outer pages, ArticleCount is filled on reply.
+type NewsReply struct {
+	ArticleCount int            `xml:"ArticleCount"`
+	Articles     []ReplyArticle `xml:"Articles>item"`
+}
+
+type ReplyMedia struct {
+	MediaID string `xml:"MediaId"`
+}
+
+type ReplyVideo struct {
+	MediaID     string `xml:"MediaId"`
+	Title       string `xml:"Title,omitempty"`
+	Description string `xml:"Description,omitempty"`
+	// ThumbMediaID is not replied passively, but required by customer service message
+	// if replied after deadline.
+	ThumbMediaID string `xml:"-"`
+}
+
+type ReplyMusic struct {
+	Title        string `xml:"Title,omitempty"`
+	Description  string `xml:"Description,omitempty"`
+	MusicURL     string `xml:"MusicUrl,omitempty"`
+	HQMusicURL   string `xml:"HQMusicUrl,omitempty"`
+	ThumbMediaID string `xml:"ThumbMediaId"`
+}
+
+type ReplyArticle struct {
+	Title       string `xml:"Title"`
+	Description string `xml:"Description"`
+	PicURL      string `xml:"PicUrl"`
+	URL         string `xml:"Url"`
+}
+
+// replies carry no message id
+func (c *ImageReply) GetMessageID() int64 { return 0 }
+func (c *VoiceReply) GetMessageID() int64 { return 0 }
+func (c *VideoReply) GetMessageID() int64 { return 0 }
+func (c *MusicReply) GetMessageID() int64 { return 0 }
+func (c *NewsReply) GetMessageID() int64  { return 0 }
+
+// replyType of content replied.
+func replyType(c Content) string {
+	switch c.(type) {
+	case *Text:
+		return TypeText
+	case *ImageReply:
+		return TypeImage
+	case *VoiceReply:
+		return TypeVoice
+	case *VideoReply:
+		return TypeVideo
+	case *MusicReply:
+		return TypeMusic
+	case *NewsReply:
+		return TypeNews
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+package message
+
+// wechat message push
+// https://mp.weixin.qq.com/wiki?t=resource/res_main&id=mp1421140453
+// https://mp.weixin.qq.com/wiki?t=resource/res_main&id=mp1421135319
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/MenInBack/weshin/crypto"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// Server receives messages pushed from wechat and replies with Handler.
+type Server struct {
+	AppID          string
+	Token          string
+	EncodingAESKey string // required for safe mode only
+	Handler        MessageHandler
+
+	// Dedupe replies retried messages with the first reply if set.
+	Dedupe *Deduplicator
+
+	// Deadline for Handler if set, wechat waits for reply no more than 5 seconds.
+	// Once exceeded, "success" is replied at once, and the reply of Handler
+	// is sent later by customer service api with access token from TokenStorage.
+	Deadline     time.Duration
+	TokenStorage wx.AccessTokenStorage
+	// AllowList rejects requests from ips other than wechat servers if set.
+	AllowList *IPAllowList
+	// Errors receives errors when handling messages if set,
+	// errors are dropped if nobody is receiving.
+	Errors chan error
+
+	sendCustom func(accessToken string, msg *CustomMessage, timeout int) error // SendCustomMessage if nil
+}
+
+// Start serves message push on path at address.
+func (s *Server) Start(address, path string) chan error {
+	if s.Errors == nil {
+		s.Errors = make(chan error)
+	}
+	if s.AllowList != nil && s.AllowList.Errors == nil {
+		s.AllowList.Errors = s.Errors
+	}
+
+	go func() {
+		http.Handle(path, s)
+		err := http.ListenAndServe(address, nil)
+		if err != nil {
+			s.Errors <- err
+		}
+	}()
+
+	return s.Errors
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.AllowList != nil {
+		ok, err := s.AllowList.Allowed(req)
+		if err != nil {
+			s.notifyError(err)
+		}
+		if !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	p := getParameter(req)
+	if !s.checkSignature(p) {
+		http.Error(w, "invalid signature", http.StatusBadRequest)
+		return
+	}
+
+	// server validation
+	if req.Method == http.MethodGet {
+		w.Write([]byte(p.echostr))
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		s.notifyError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data := body
+	var encoding *crypto.MessageCrypto
+	if p.encryptType == EncryptTypeAES {
+		encoding, err = crypto.New(s.EncodingAESKey, s.Token, s.AppID)
+		if err != nil {
+			s.notifyError(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data, err = encoding.Decrypt(body, p.msgSignature, p.nonce, p.timestamp)
+		if err != nil {
+			s.notifyError(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	msg, err := Unmarshal(data)
+	if err != nil {
+		s.notifyError(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reply, err := s.reply(msg)
+	if err != nil {
+		s.notifyError(err)
+	}
+	if len(reply) == 0 {
+		w.Write([]byte(replyEmpty))
+		return
+	}
+
+	if encoding != nil {
+		reply, err = encoding.Encrypt(reply, p.nonce, p.timestamp)
+		if err != nil {
+			s.notifyError(err)
+			w.Write([]byte(replyEmpty))
+			return
+		}
+	}
+	w.Write(reply)
+}
+
+func (s *Server) reply(msg *Message) ([]byte, error) {
+	handle := func() ([]byte, error) {
+		return s.handle(msg)
+	}
+	if s.Dedupe != nil {
+		return s.Dedupe.Do(msg, handle)
+	}
+	return handle()
+}
+
+// handle message and marshal the reply
+func (s *Server) handle(msg *Message) ([]byte, error) {
+	if s.Handler == nil {
+		return nil, errors.New("no message handler")
+	}
+
+	if s.Deadline <= 0 {
+		return marshalReply(msg, s.Handler(msg))
+	}
+
+	chReply := make(chan *Message, 1)
+	go func() {
+		chReply <- s.Handler(msg)
+	}()
+
+	select {
+	case reply := <-chReply:
+		return marshalReply(msg, reply)
+	case <-time.After(s.Deadline):
+		go s.sendLater(msg, chReply)
+		return nil, nil
+	}
+}
+
+// sendLater sends reply exceeding deadline by customer service api.
+func (s *Server) sendLater(msg *Message, chReply chan *Message) {
+	reply := <-chReply
+	if reply == nil {
+		return
+	}
+	completeReply(msg, reply)
+
+	if s.TokenStorage == nil {
+		s.notifyError(wx.ConfigError{InvalidConfig: "TokenStorage"})
+		return
+	}
+	custom, err := NewCustomMessage(reply)
+	if err != nil {
+		s.notifyError(err)
+		return
+	}
+	send := s.sendCustom
+	if send == nil {
+		send = SendCustomMessage
+	}
+	err = send(s.TokenStorage.GetAccessToken(), custom, 0)
+	if err != nil {
+		s.notifyError(err)
+	}
+}
+
+func marshalReply(msg, reply *Message) ([]byte, error) {
+	if reply == nil {
+		return nil, nil
+	}
+	completeReply(msg, reply)
+
+	return Marshal(reply)
+}
+
+// completeReply fills meta of reply missing from received message.
+func completeReply(msg, reply *Message) {
+	if reply.FromUserName == "" {
+		reply.FromUserName = msg.ToUserName
+	}
+	if reply.ToUserName == "" {
+		reply.ToUserName = msg.FromUserName
+	}
+	if reply.CreateTime == 0 {
+		reply.CreateTime = time.Now().Unix()
+	}
+	if reply.MessageType == "" {
+		reply.MessageType = replyType(reply.Content)
+	}
+	if news, ok := reply.Content.(*NewsReply); ok {
+		news.ArticleCount = len(news.Articles)
+	}
+}
+
+func (s *Server) checkSignature(p *messageParameter) bool {
+	sig := crypto.Signature([]string{p.timestamp, p.nonce, s.Token})
+	return string(sig) == p.signature
+}
+
+func (s *Server) notifyError(err error) {
+	if s.Errors == nil {
+		return
+	}
+	select {
+	case s.Errors <- wx.NotifyError{Err: err}:
+	default:
+	}
+}
+
+type messageParameter struct {
+	timestamp    string
+	nonce        string
+	encryptType  string
+	signature    string
+	msgSignature string
+	echostr      string
+}
+
+func getParameter(req *http.Request) *messageParameter {
+	queries := req.URL.Query()
+	return &messageParameter{
+		timestamp:    queries.Get("timestamp"),
+		nonce:        queries.Get("nonce"),
+		encryptType:  queries.Get("encrypt_type"),
+		signature:    queries.Get("signature"),
+		msgSignature: queries.Get("msg_signature"),
+		echostr:      queries.Get("echostr"),
+	}
+}
@@ -0,0 +1,278 @@
+package message
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MenInBack/weshin/crypto"
+	"github.com/MenInBack/weshin/wx"
+)
+
+const (
+	token     = "spamtest"
+	nonce     = "1320562132"
+	timestamp = "1409735669"
+	textXML   = `<xml><ToUserName><![CDATA[gh_10f6c3c3ac5a]]></ToUserName><FromUserName><![CDATA[oyORnuP8q7ou2gfYjqLzSIWZf0rs]]></FromUserName><CreateTime>1409735668</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[hello]]></Content><MsgId>6054768590064713728</MsgId></xml>`
+	eventXML  = `<xml><ToUserName><![CDATA[gh_10f6c3c3ac5a]]></ToUserName><FromUserName><![CDATA[oyORnuP8q7ou2gfYjqLzSIWZf0rs]]></FromUserName><CreateTime>1409735668</CreateTime><MsgType><![CDATA[event]]></MsgType><Event><![CDATA[subscribe]]></Event></xml>`
+)
+
+func pushMessage(s http.Handler, body string) string {
+	sig := string(crypto.Signature([]string{timestamp, nonce, token}))
+	req := httptest.NewRequest("POST", "/?signature="+sig+"&timestamp="+timestamp+"&nonce="+nonce, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	data, _ := ioutil.ReadAll(w.Result().Body)
+	return string(data)
+}
+
+func TestServerReply(t *testing.T) {
+	s := &Server{
+		Token: token,
+		Handler: func(msg *Message) *Message {
+			if msg.MessageType != TypeText {
+				return nil
+			}
+			return &Message{
+				Meta:    Meta{MessageType: TypeText},
+				Content: &Text{Content: "echo " + msg.Content.(*Text).Content},
+			}
+		},
+	}
+
+	reply, err := Unmarshal([]byte(pushMessage(s, textXML)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ToUserName != "oyORnuP8q7ou2gfYjqLzSIWZf0rs" || reply.FromUserName != "gh_10f6c3c3ac5a" {
+		t.Errorf("unexpected reply meta: %+v", reply.Meta)
+	}
+	if text, ok := reply.Content.(*Text); !ok || text.Content != "echo hello" {
+		t.Errorf("unexpected reply content: %+v", reply.Content)
+	}
+
+	if r := pushMessage(s, eventXML); r != replyEmpty {
+		t.Error("expect empty reply for event, got: ", r)
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	var handled int32
+	s := &Server{
+		Token: token,
+		Handler: func(msg *Message) *Message {
+			n := atomic.AddInt32(&handled, 1)
+			return &Message{
+				Meta:    Meta{MessageType: TypeText},
+				Content: &Text{Content: string('0' + n)},
+			}
+		},
+		Dedupe: &Deduplicator{Store: NewMemoryReplyStore()},
+	}
+
+	for _, body := range []string{textXML, eventXML} {
+		atomic.StoreInt32(&handled, 0)
+		replies := make([]string, 3)
+		var wg sync.WaitGroup
+		for i := range replies {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				replies[i] = pushMessage(s, body)
+			}(i)
+		}
+		wg.Wait()
+
+		if handled != 1 {
+			t.Errorf("message handled %d times", handled)
+		}
+		for _, r := range replies {
+			if r != replies[0] {
+				t.Error("duplicated message replied differently: ", r)
+			}
+		}
+	}
+}
+
+func TestServerEncryptedReply(t *testing.T) {
+	const (
+		appID          = "wx2c2769f8efd9abc2"
+		encodingAESKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	)
+	s := &Server{
+		AppID:          appID,
+		Token:          token,
+		EncodingAESKey: encodingAESKey,
+		Handler: func(msg *Message) *Message {
+			return &Message{
+				Meta:    Meta{MessageType: TypeText},
+				Content: &Text{Content: "echo " + msg.Content.(*Text).Content},
+			}
+		},
+	}
+
+	encoding, err := crypto.New(encodingAESKey, token, appID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := encoding.Encrypt([]byte(textXML), nonce, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encrypted struct {
+		MsgSignature string `xml:"MsgSignature"`
+	}
+	err = xml.Unmarshal(body, &encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := string(crypto.Signature([]string{timestamp, nonce, token}))
+	req := httptest.NewRequest("POST", "/?signature="+sig+"&timestamp="+timestamp+"&nonce="+nonce+
+		"&encrypt_type=aes&msg_signature="+encrypted.MsgSignature, bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+
+	decoding, err := crypto.New(encodingAESKey, token, appID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := decoding.Decrypt(w.Body.Bytes(), "", "", "")
+	if err != nil {
+		t.Fatal("decrypt reply: ", err)
+	}
+	reply, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ToUserName != "oyORnuP8q7ou2gfYjqLzSIWZf0rs" {
+		t.Error("unexpected reply ToUserName: ", reply.ToUserName)
+	}
+	if text, ok := reply.Content.(*Text); !ok || text.Content != "echo hello" {
+		t.Errorf("unexpected reply content: %+v", reply.Content)
+	}
+}
+
+type testTokenStorage string
+
+func (s testTokenStorage) SetAccessToken(token string, expiresIn int64) {}
+func (s testTokenStorage) GetAccessToken() string                       { return string(s) }
+
+func TestServerDeadline(t *testing.T) {
+	sent := make(chan *CustomMessage, 1)
+	release := make(chan struct{})
+	s := &Server{
+		Token:        token,
+		Deadline:     20 * time.Millisecond,
+		TokenStorage: testTokenStorage("ACCESS_TOKEN"),
+		Handler: func(msg *Message) *Message {
+			if msg.Content.(*Text).Content == "slow" {
+				<-release
+			}
+			return &Message{Content: &Text{Content: "echo " + msg.Content.(*Text).Content}}
+		},
+		sendCustom: func(accessToken string, msg *CustomMessage, timeout int) error {
+			if accessToken != "ACCESS_TOKEN" {
+				t.Error("unexpected access token: ", accessToken)
+			}
+			sent <- msg
+			return nil
+		},
+	}
+
+	// replied inline in time
+	reply, err := Unmarshal([]byte(pushMessage(s, textXML)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, ok := reply.Content.(*Text); !ok || text.Content != "echo hello" {
+		t.Errorf("unexpected reply content: %+v", reply.Content)
+	}
+
+	// replied by customer service api after deadline
+	if r := pushMessage(s, strings.Replace(textXML, "hello", "slow", 1)); r != replyEmpty {
+		t.Error("expect empty reply after deadline, got: ", r)
+	}
+	close(release)
+	select {
+	case msg := <-sent:
+		if msg.ToUser != "oyORnuP8q7ou2gfYjqLzSIWZf0rs" || msg.MessageType != TypeText || msg.Text.Content != "echo slow" {
+			t.Errorf("unexpected custom message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reply not sent by customer service api")
+	}
+
+	select {
+	case msg := <-sent:
+		t.Errorf("unexpected custom message: %+v", msg)
+	default:
+	}
+}
+
+func TestServerDeadlineVideo(t *testing.T) {
+	sent := make(chan *CustomMessage, 1)
+	s := &Server{
+		Token:        token,
+		Deadline:     20 * time.Millisecond,
+		TokenStorage: testTokenStorage("ACCESS_TOKEN"),
+		Errors:       make(chan error, 1),
+		Handler: func(msg *Message) *Message {
+			reply := &VideoReply{ReplyVideo{MediaID: "video"}}
+			switch msg.Content.(*Text).Content {
+			case "slow":
+				time.Sleep(40 * time.Millisecond)
+				reply.Video.ThumbMediaID = "thumb"
+			case "slow without thumb":
+				time.Sleep(40 * time.Millisecond)
+			}
+			return &Message{Content: reply}
+		},
+		sendCustom: func(accessToken string, msg *CustomMessage, timeout int) error {
+			if err := msg.Validate(); err != nil {
+				return err
+			}
+			sent <- msg
+			return nil
+		},
+	}
+
+	// thumb is not replied passively
+	if r := pushMessage(s, textXML); !strings.Contains(r, "<Video><MediaId>video</MediaId></Video>") || strings.Contains(r, "ThumbMediaId") {
+		t.Error("unexpected video reply: ", r)
+	}
+
+	pushMessage(s, strings.Replace(textXML, "hello", "slow", 1))
+	select {
+	case msg := <-sent:
+		if msg.MessageType != TypeVideo || msg.Video.MediaID != "video" || msg.Video.ThumbMediaID != "thumb" {
+			t.Errorf("unexpected custom message: %+v", msg.Video)
+		}
+	case err := <-s.Errors:
+		t.Fatal(err)
+	case <-time.After(time.Second):
+		t.Fatal("reply not sent by customer service api")
+	}
+
+	pushMessage(s, strings.Replace(textXML, "hello", "slow without thumb", 1))
+	select {
+	case msg := <-sent:
+		t.Errorf("unexpected custom message: %+v", msg.Video)
+	case err := <-s.Errors:
+		if e, ok := err.(wx.NotifyError); !ok || !strings.Contains(e.Err.Error(), "ThumbMediaID") {
+			t.Error("unexpected error: ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expect error of video reply without thumb")
+	}
+}
@@ -0,0 +1,192 @@
+package message
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const defaultSessionTTL = 30 * time.Minute
+
+// SessionStore holds session data of users.
+type SessionStore interface {
+	// SetSession saves session data, which should expire after ttl.
+	SetSession(key string, data []byte, ttl time.Duration)
+	// GetSession returns session data saved, ok is false if not found or expired.
+	GetSession(key string) (data []byte, ok bool)
+	// DeleteSession when session ended.
+	DeleteSession(key string)
+}
+
+// Session holds conversation state of a user with official account.
+// Session is not safe for messages of the same user handled concurrently,
+// the last saved wins.
+type Session struct {
+	key     string
+	data    sessionData
+	changed bool
+	ended   bool
+}
+
+type sessionData struct {
+	Step   string                     `json:"step,omitempty"`
+	Values map[string]json.RawMessage `json:"values,omitempty"`
+}
+
+// Get value of key into v, ok is false if not set.
+func (s *Session) Get(key string, v interface{}) (ok bool, err error) {
+	data, ok := s.data.Values[key]
+	if !ok {
+		return false, nil
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Set value of key, v should be json marshalable.
+func (s *Session) Set(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if s.data.Values == nil {
+		s.data.Values = make(map[string]json.RawMessage)
+	}
+	s.data.Values[key] = data
+	s.changed = true
+	return nil
+}
+
+// Delete value of key.
+func (s *Session) Delete(key string) {
+	delete(s.data.Values, key)
+	s.changed = true
+}
+
+// Step of the flow user is in, empty if not in any flow.
+func (s *Session) Step() string {
+	return s.data.Step
+}
+
+// Goto step for the next message of user.
+func (s *Session) Goto(step string) {
+	s.data.Step = step
+	s.changed = true
+}
+
+// End session, with step and values cleared,
+// changes made before are dropped, changes after start a new session.
+func (s *Session) End() {
+	s.data = sessionData{}
+	s.changed = false
+	s.ended = true
+}
+
+// SessionFrom returns session of user sent msg,
+// nil if message is not handled by Sessions.
+func SessionFrom(msg *Message) *Session {
+	s, _ := msg.Context().Value(sessionKey).(*Session)
+	return s
+}
+
+// Sessions loads session of user into message context for handlers,
+// and saves it after handled. Sessions are keyed by FromUserName with
+// ToUserName, or with authorizer appid in component mode.
+type Sessions struct {
+	Store SessionStore
+	TTL   time.Duration // defaultSessionTTL if not set, refreshed by each message
+}
+
+// Handle wraps handler with sessions, see SessionFrom.
+func (ss *Sessions) Handle(handler MessageHandler) MessageHandler {
+	return func(msg *Message) *Message {
+		s := ss.load(msg)
+		ctx := msg.Context()
+		reply := handler(msg.WithContext(contextWithSession(ctx, s)))
+		ss.save(s)
+		return reply
+	}
+}
+
+func (ss *Sessions) load(msg *Message) *Session {
+	account := AuthorizerAppID(msg.Context())
+	if account == "" {
+		account = msg.ToUserName
+	}
+	s := &Session{key: account + ":" + msg.FromUserName}
+
+	data, ok := ss.Store.GetSession(s.key)
+	if ok && json.Unmarshal(data, &s.data) != nil {
+		s.data = sessionData{} // drop broken session
+	}
+	return s
+}
+
+func (ss *Sessions) save(s *Session) {
+	if s.ended {
+		ss.Store.DeleteSession(s.key)
+		if !s.changed {
+			return
+		}
+	}
+
+	data, err := json.Marshal(s.data)
+	if err != nil {
+		return
+	}
+	ss.Store.SetSession(s.key, data, ss.ttl())
+}
+
+func (ss *Sessions) ttl() time.Duration {
+	if ss.TTL > 0 {
+		return ss.TTL
+	}
+	return defaultSessionTTL
+}
+
+// StepHandler handles message of user in step, and moves session to the next step.
+type StepHandler func(msg *Message, s *Session) *Message
+
+// Flow runs step handlers by step of session, as a state machine.
+// Messages of users not in any step of flow are passed to the next handler,
+// which starts the flow by Session.Goto.
+type Flow map[string]StepHandler
+
+// Handle wraps next handler with flow, should be wrapped by Sessions.Handle.
+func (f Flow) Handle(next MessageHandler) MessageHandler {
+	return func(msg *Message) *Message {
+		s := SessionFrom(msg)
+		if s != nil {
+			if h, ok := f[s.Step()]; ok {
+				return h(msg, s)
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		return next(msg)
+	}
+}
+
+// MemorySessionStore implements SessionStore in process memory.
+type MemorySessionStore struct {
+	cache memoryCache
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return new(MemorySessionStore)
+}
+
+func (s *MemorySessionStore) SetSession(key string, data []byte, ttl time.Duration) {
+	s.cache.set(key, data, ttl)
+}
+
+func (s *MemorySessionStore) GetSession(key string) ([]byte, bool) {
+	return s.cache.get(key)
+}
+
+func (s *MemorySessionStore) DeleteSession(key string) {
+	s.cache.delete(key)
+}
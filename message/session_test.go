@@ -0,0 +1,99 @@
+package message
+
+import (
+	"testing"
+)
+
+func textMessage(from, content string) *Message {
+	return &Message{
+		Meta:    Meta{FromUserName: from, ToUserName: "gh_10f6c3c3ac5a", MessageType: TypeText},
+		Content: &Text{Content: content},
+	}
+}
+
+func replyText(content string) *Message {
+	return &Message{
+		Meta:    Meta{MessageType: TypeText},
+		Content: &Text{Content: content},
+	}
+}
+
+func TestSessionFlow(t *testing.T) {
+	flow := Flow{
+		"phone": func(msg *Message, s *Session) *Message {
+			s.Set("phone", msg.Content.(*Text).Content)
+			s.Goto("confirm")
+			return replyText("confirm?")
+		},
+		"confirm": func(msg *Message, s *Session) *Message {
+			var phone string
+			s.Get("phone", &phone)
+			s.End()
+			return replyText("bound " + phone)
+		},
+	}
+	sessions := &Sessions{Store: NewMemorySessionStore()}
+	handler := sessions.Handle(flow.Handle(func(msg *Message) *Message {
+		if msg.Content.(*Text).Content == "bind" {
+			SessionFrom(msg).Goto("phone")
+			return replyText("phone?")
+		}
+		return nil
+	}))
+
+	steps := []struct {
+		from, content, reply string
+	}{
+		{"alice", "bind", "phone?"},
+		{"bob", "hello", ""},
+		{"alice", "13800000000", "confirm?"},
+		{"bob", "bind", "phone?"},
+		{"alice", "yes", "bound 13800000000"},
+		{"alice", "yes", ""},
+	}
+	for i, step := range steps {
+		reply := handler(textMessage(step.from, step.content))
+		var got string
+		if reply != nil {
+			got = reply.Content.(*Text).Content
+		}
+		if got != step.reply {
+			t.Errorf("step %d: expect reply %q, got %q", i, step.reply, got)
+		}
+	}
+}
+
+func TestSessionEnd(t *testing.T) {
+	store := NewMemorySessionStore()
+	sessions := &Sessions{Store: store}
+	handler := sessions.Handle(func(msg *Message) *Message {
+		s := SessionFrom(msg)
+		switch msg.Content.(*Text).Content {
+		case "set":
+			s.Set("k", "v")
+		case "set and end":
+			s.Set("k", "v")
+			s.End()
+		case "end and set":
+			s.End()
+			s.Set("k", "v")
+		}
+		return nil
+	})
+	key := "gh_10f6c3c3ac5a:alice"
+
+	for _, c := range []struct {
+		content string
+		saved   bool
+	}{
+		{"set", true},
+		{"set and end", false},
+		{"end and set", true},
+	} {
+		store.DeleteSession(key)
+		handler(textMessage("alice", c.content))
+		if _, ok := store.GetSession(key); ok != c.saved {
+			t.Errorf("%s: session saved %v, expect %v", c.content, ok, c.saved)
+		}
+	}
+}
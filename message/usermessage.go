@@ -1,5 +1,5 @@
 package message
 
-import ()
-
+// MessageHandler handles message from user and returns the reply,
+// nil for no reply.
 type MessageHandler func(*Message) *Message
@@ -0,0 +1,92 @@
+package pay
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sort"
+	"strings"
+)
+
+// PlainNotice decrypts req_info of refund notice with payment key,
+// for notices recorded to be encrypted again with another key by ResignNotice.
+// Notice without req_info is returned as it is.
+func PlainNotice(body []byte, key string) ([]byte, error) {
+	fields, e := parseToFields(bytes.NewReader(body))
+	if e != nil {
+		return nil, e
+	}
+	info, ok := fields["req_info"]
+	if !ok {
+		return body, nil
+	}
+
+	data, e := decodeNoticeMessage(info, key)
+	if e != nil {
+		return nil, e
+	}
+	fields["req_info"] = string(data)
+
+	return marshalNotice(fields), nil
+}
+
+// ResignNotice signs pay notice with payment key, or encrypts req_info of
+// refund notice decrypted by PlainNotice, for replaying recorded notices in tests.
+func ResignNotice(body []byte, key string) ([]byte, error) {
+	fields, e := parseToFields(bytes.NewReader(body))
+	if e != nil {
+		return nil, e
+	}
+
+	if info, ok := fields["req_info"]; ok {
+		fields["req_info"], e = encodeNoticeMessage([]byte(info), key)
+		if e != nil {
+			return nil, e
+		}
+		return marshalNotice(fields), nil
+	}
+
+	signType := MD5
+	if st, ok := fields["sign_type"]; ok {
+		signType = SignType(st)
+	}
+	fs := make([]field, 0, len(fields))
+	for n, v := range fields {
+		switch n {
+		case "sign", "sign_type":
+			continue
+		}
+		fs = append(fs, field{n, v})
+	}
+
+	s, e := sign(fs, key, signType)
+	if e != nil {
+		return nil, e
+	}
+	fields["sign"] = s
+
+	return marshalNotice(fields), nil
+}
+
+// marshal notice fields in CDATA if possible, sorted by name.
+func marshalNotice(fields map[string]string) []byte {
+	names := make([]string, 0, len(fields))
+	for n := range fields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBufferString("<xml>")
+	for _, n := range names {
+		v := fields[n]
+		buf.WriteString("<" + n + ">")
+		if strings.Contains(v, "]]>") {
+			// escaped as text, such as plain req_info in xml with CDATA
+			xml.EscapeText(buf, []byte(v))
+		} else {
+			buf.WriteString("<![CDATA[" + v + "]]>")
+		}
+		buf.WriteString("</" + n + ">")
+	}
+	buf.WriteString("</xml>")
+	return buf.Bytes()
+}
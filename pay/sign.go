@@ -57,8 +57,13 @@ type field struct {
 //（2）对商户key做md5，得到32位小写key* ( key设置路径：微信商户平台(pay.weixin.qq.com)-->账户设置-->API安全-->密钥设置 )
 //（3）用key*对加密串B做AES-256-ECB解密
 func decodeNoticeMessage(info, key string) ([]byte, error) {
+	info = strings.TrimRight(info, "=")
 	cipher := make([]byte, base64.RawStdEncoding.DecodedLen(len(info)))
-	base64.RawStdEncoding.Decode(cipher, []byte(info))
+	n, e := base64.RawStdEncoding.Decode(cipher, []byte(info))
+	if e != nil {
+		return nil, e
+	}
+	cipher = cipher[:n]
 
 	hashKey := md5.Sum([]byte(key))
 	hexKey := make([]byte, hex.EncodedLen(len(hashKey)))
@@ -69,18 +74,46 @@ func decodeNoticeMessage(info, key string) ([]byte, error) {
 		return nil, e
 	}
 
-	// padding cipher
-	if len(cipher)%block.BlockSize() != 0 {
-		pad := make([]byte, block.BlockSize()-len(cipher)%block.BlockSize())
-		cipher = append(cipher, pad...)
+	bs := block.BlockSize()
+	if len(cipher) == 0 || len(cipher)%bs != 0 {
+		return nil, wx.WeshinError{Detail: "invalid encrypted message size"}
 	}
-	buf := cipher
 
 	// aes-256-ecb decrypt
-	for len(cipher) > 0 {
-		block.Decrypt(buf, cipher)
-		cipher = cipher[block.BlockSize():]
+	buf := make([]byte, len(cipher))
+	for i := 0; i < len(cipher); i += bs {
+		block.Decrypt(buf[i:i+bs], cipher[i:i+bs])
+	}
+
+	// drop PKCS#7 padding
+	pad := int(buf[len(buf)-1])
+	if pad <= 0 || pad > bs || !bytes.Equal(buf[len(buf)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, wx.WeshinError{Detail: "invalid padding of decrypted message"}
+	}
+
+	return buf[:len(buf)-pad], nil
+}
+
+// reverse of decodeNoticeMessage, with PKCS#7 padding
+func encodeNoticeMessage(data []byte, key string) (string, error) {
+	hashKey := md5.Sum([]byte(key))
+	hexKey := make([]byte, hex.EncodedLen(len(hashKey)))
+	hex.Encode(hexKey, hashKey[:])
+
+	block, e := aes.NewCipher(hexKey)
+	if e != nil {
+		return "", e
+	}
+
+	pad := block.BlockSize() - len(data)%block.BlockSize()
+	buf := make([]byte, 0, len(data)+pad)
+	buf = append(buf, data...)
+	buf = append(buf, bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	// aes-256-ecb encrypt
+	for b := buf; len(b) > 0; b = b[block.BlockSize():] {
+		block.Encrypt(b, b)
 	}
 
-	return buf, nil
+	return base64.StdEncoding.EncodeToString(buf), nil
 }
@@ -1,6 +1,7 @@
 package pay
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"testing"
@@ -34,15 +35,62 @@ func TestSign(t *testing.T) {
 	fmt.Println(s)
 }
 
+// req_info of refund notice
+const refundInfo = `<root><out_refund_no><![CDATA[131811191610442717309]]></out_refund_no><out_trade_no><![CDATA[71106718111915575302817]]></out_trade_no><refund_account><![CDATA[REFUND_SOURCE_RECHARGE_FUNDS]]></refund_account><refund_fee><![CDATA[3960]]></refund_fee><refund_id><![CDATA[50000408942018111907145868882]]></refund_id><refund_recv_accout><![CDATA[支付用户零钱]]></refund_recv_accout><refund_request_source><![CDATA[API]]></refund_request_source><refund_status><![CDATA[SUCCESS]]></refund_status><settlement_refund_fee><![CDATA[3960]]></settlement_refund_fee><settlement_total_fee><![CDATA[3960]]></settlement_total_fee><success_time><![CDATA[2018-11-19 16:24:13]]></success_time><total_fee><![CDATA[3960]]></total_fee><transaction_id><![CDATA[4200000215201811190261405420]]></transaction_id></root>`
+
 func TestDecodeMessage(t *testing.T) {
-	info := `T87GAHG17TGAHG1TGHAHAHA1Y1CIOA9UGJH1GAHV871HAGAGQYQQPOOJMXNBCXBVNMNMAJAA`
+	info, e := encodeNoticeMessage([]byte(refundInfo), key)
+	if e != nil {
+		t.Fatal(e)
+	}
 
 	data, e := decodeNoticeMessage(info, key)
 	if e != nil {
-		t.Error(e)
-		return
+		t.Fatal(e)
+	}
+	if string(data) != refundInfo {
+		t.Errorf("round trip mismatch: %q", data)
 	}
 
-	fmt.Println(data)
+	if _, e = decodeNoticeMessage(info, "another key"); e == nil {
+		t.Error("expect error decoding with another key")
+	}
+}
 
+func TestPlainNotice(t *testing.T) {
+	info, e := encodeNoticeMessage([]byte(refundInfo), key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	body := marshalNotice(map[string]string{
+		"return_code": "SUCCESS",
+		"appid":       "wx2421b1c4370ec43b",
+		"mch_id":      "10000100",
+		"nonce_str":   "TeqClE3i0mvn3DrK",
+		"req_info":    info,
+	})
+
+	plain, e := PlainNotice(body, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	fields, e := parseToFields(bytes.NewReader(plain))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if fields["req_info"] != refundInfo {
+		t.Errorf("unexpected plain req_info: %q", fields["req_info"])
+	}
+
+	resigned, e := ResignNotice(plain, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	again, e := PlainNotice(resigned, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(again) != string(plain) {
+		t.Errorf("round trip mismatch: %s", again)
+	}
 }
@@ -0,0 +1,92 @@
+package referral
+
+import (
+	"sync"
+)
+
+// Store holds attributions of users and funnel counts of scenes.
+type Store interface {
+	// Attribute saves attribution if none saved for the user, returns false if saved already.
+	Attribute(a *Attribution) (bool, error)
+	// GetAttribution of user, nil if none.
+	GetAttribution(openID string) (*Attribution, error)
+	// AddCounts adds delta to funnel counts of scene.
+	AddCounts(scene string, delta Funnel) error
+	// Funnels returns funnel counts by scene.
+	Funnels() (map[string]*Funnel, error)
+}
+
+// Attribution of user to the scene first subscribed or scanned through.
+type Attribution struct {
+	OpenID string `json:"openID"`
+	Scene  string `json:"scene"`
+	Event  string `json:"event"` // subscribe or SCAN
+	At     int64  `json:"at"`    // unix time of event
+}
+
+// Funnel counts of scene.
+type Funnel struct {
+	Scans        int64 `json:"scans"`        // scans by followers and new subscribers
+	Subscribes   int64 `json:"subscribes"`   // new subscribes by scanning
+	Unsubscribes int64 `json:"unsubscribes"` // unsubscribes of users attributed to scene
+}
+
+func (f *Funnel) add(delta Funnel) {
+	f.Scans += delta.Scans
+	f.Subscribes += delta.Subscribes
+	f.Unsubscribes += delta.Unsubscribes
+}
+
+// MemoryStore keeps attributions in memory, for single process.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	attributions map[string]Attribution
+	funnels      map[string]Funnel
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		attributions: make(map[string]Attribution),
+		funnels:      make(map[string]Funnel),
+	}
+}
+
+func (s *MemoryStore) Attribute(a *Attribution) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.attributions[a.OpenID]; ok {
+		return false, nil
+	}
+	s.attributions[a.OpenID] = *a
+	return true, nil
+}
+
+func (s *MemoryStore) GetAttribution(openID string) (*Attribution, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.attributions[openID]
+	if !ok {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+func (s *MemoryStore) AddCounts(scene string, delta Funnel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.funnels[scene]
+	f.add(delta)
+	s.funnels[scene] = f
+	return nil
+}
+
+func (s *MemoryStore) Funnels() (map[string]*Funnel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	funnels := make(map[string]*Funnel, len(s.funnels))
+	for scene, f := range s.funnels {
+		f := f
+		funnels[scene] = &f
+	}
+	return funnels, nil
+}
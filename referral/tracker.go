@@ -0,0 +1,88 @@
+package referral
+
+// attribute users to scenes of qrcodes: the first scene a user subscribed or
+// scanned through is kept as attribution, and each scene counts scans,
+// new subscribes and unsubscribes of users attributed to it.
+// Retried events should be deduplicated by message.Deduplicator of server.
+
+import (
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// Tracker feeds subscribe, SCAN and unsubscribe events into Store.
+type Tracker struct {
+	Store Store
+
+	// Errors receives errors when handling events if set,
+	// errors are dropped if nobody is receiving.
+	Errors chan error
+}
+
+// Handle tracks events, messages are passed to next handler if not nil.
+func (t *Tracker) Handle(next message.MessageHandler) message.MessageHandler {
+	return func(msg *message.Message) *message.Message {
+		if e, ok := msg.Content.(*message.Event); ok {
+			if err := t.Track(msg.FromUserName, e, msg.CreateTime); err != nil {
+				t.notifyError(err)
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		return next(msg)
+	}
+}
+
+// Track event of user at time, events other than subscribe, SCAN and unsubscribe are ignored.
+func (t *Tracker) Track(openID string, e *message.Event, at int64) error {
+	if e.Event == message.EventUnsubscribe {
+		a, err := t.Store.GetAttribution(openID)
+		if err != nil || a == nil {
+			return err
+		}
+		return t.Store.AddCounts(a.Scene, Funnel{Unsubscribes: 1})
+	}
+
+	scene, ok := e.QRScene()
+	if !ok {
+		return nil
+	}
+
+	_, err := t.Store.Attribute(&Attribution{
+		OpenID: openID,
+		Scene:  scene,
+		Event:  e.Event,
+		At:     at,
+	})
+	if err != nil {
+		return err
+	}
+
+	delta := Funnel{Scans: 1}
+	if e.Event == message.EventSubscribe {
+		delta.Subscribes = 1
+	}
+	return t.Store.AddCounts(scene, delta)
+}
+
+// Attribution of user, nil if user is not attributed to any scene.
+func (t *Tracker) Attribution(openID string) (*Attribution, error) {
+	return t.Store.GetAttribution(openID)
+}
+
+// Funnels returns funnel counts by scene.
+func (t *Tracker) Funnels() (map[string]*Funnel, error) {
+	return t.Store.Funnels()
+}
+
+func (t *Tracker) notifyError(err error) {
+	if t.Errors == nil {
+		return
+	}
+	select {
+	case t.Errors <- wx.NotifyError{Err: err}:
+	default:
+	}
+}
@@ -0,0 +1,92 @@
+package referral
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := &Tracker{Store: NewMemoryStore()}
+	var passed int
+	h := tracker.Handle(func(msg *message.Message) *message.Message {
+		passed++
+		return nil
+	})
+
+	push := func(openID, event, key string, at int64) {
+		h(&message.Message{
+			Meta:    message.Meta{FromUserName: openID, MessageType: message.TypeEvent, CreateTime: at},
+			Content: &message.Event{Event: event, EventKey: key},
+		})
+	}
+	push("user1", message.EventSubscribe, "qrscene_poster", 1)
+	push("user2", message.EventSubscribe, "qrscene_staff_42", 2)
+	push("user1", message.EventScan, "staff_42", 3) // follower scans another code
+	push("user3", message.EventScan, "poster", 4)   // follower subscribed before tracking
+	push("user4", message.EventSubscribe, "", 5)    // subscribed by search
+	push("user1", message.EventUnsubscribe, "", 6)
+	push("user4", message.EventUnsubscribe, "", 7)
+	push("user1", message.EventSubscribe, "qrscene_staff_42", 8) // comes back
+
+	if passed != 8 {
+		t.Error("expect all messages passed, got ", passed)
+	}
+
+	a, _ := tracker.Attribution("user1")
+	if a == nil || a.Scene != "poster" || a.Event != message.EventSubscribe || a.At != 1 {
+		t.Errorf("user1 attribution: %+v", a)
+	}
+	if a, _ := tracker.Attribution("user3"); a == nil || a.Scene != "poster" || a.Event != message.EventScan {
+		t.Errorf("user3 attribution: %+v", a)
+	}
+	if a, _ := tracker.Attribution("user4"); a != nil {
+		t.Errorf("user4 attribution: %+v", a)
+	}
+
+	funnels, _ := tracker.Funnels()
+	if f := funnels["poster"]; f == nil || *f != (Funnel{Scans: 2, Subscribes: 1, Unsubscribes: 1}) {
+		t.Errorf("poster funnel: %+v", f)
+	}
+	if f := funnels["staff_42"]; f == nil || *f != (Funnel{Scans: 3, Subscribes: 2}) {
+		t.Errorf("staff funnel: %+v", f)
+	}
+	if len(funnels) != 2 {
+		t.Errorf("funnels: %v", funnels)
+	}
+}
+
+type failingStore struct {
+	*MemoryStore
+}
+
+func (s failingStore) AddCounts(scene string, delta Funnel) error {
+	return errors.New("store failed")
+}
+
+func TestTrackerErrors(t *testing.T) {
+	tracker := &Tracker{Store: failingStore{NewMemoryStore()}, Errors: make(chan error, 1)}
+	var passed bool
+	h := tracker.Handle(func(msg *message.Message) *message.Message {
+		passed = true
+		return nil
+	})
+	h(&message.Message{
+		Meta:    message.Meta{FromUserName: "user1", MessageType: message.TypeEvent},
+		Content: &message.Event{Event: message.EventSubscribe, EventKey: "qrscene_poster"},
+	})
+
+	if !passed {
+		t.Error("expect message passed on error")
+	}
+	select {
+	case err := <-tracker.Errors:
+		if _, ok := err.(wx.NotifyError); !ok {
+			t.Errorf("unexpected error: %v", err)
+		}
+	default:
+		t.Error("expect error of store")
+	}
+}
@@ -0,0 +1,68 @@
+package replay
+
+// record inbound callbacks from wechat into fixtures,
+// and replay them into handlers signed with test keys.
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+)
+
+// fixture kind
+const (
+	// KindMessage for message push and component notify, plain or encrypted.
+	KindMessage = "message"
+	// KindPay for pay and refund notice.
+	KindPay = "pay"
+)
+
+// Fixture of an inbound callback.
+// Body is in plain text, decrypted if encrypted by wechat.
+type Fixture struct {
+	Kind     string      `json:"kind"`
+	Method   string      `json:"method"`
+	Path     string      `json:"path"`
+	Query    url.Values  `json:"query,omitempty"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body,omitempty"`
+	RecordAt int64       `json:"recordAt"`
+}
+
+// LoadFixture reads fixture from file.
+func LoadFixture(file string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	f := new(Fixture)
+	err = json.Unmarshal(data, f)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// LoadFixtures reads all fixtures in dir, in order of recording.
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures := make([]*Fixture, 0, len(files))
+	for _, file := range files {
+		f, err := LoadFixture(file)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+	sort.SliceStable(fixtures, func(i, j int) bool { return fixtures[i].RecordAt < fixtures[j].RecordAt })
+
+	return fixtures, nil
+}
@@ -0,0 +1,108 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MenInBack/weshin/crypto"
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/pay"
+)
+
+// Recorder saves callbacks into fixtures in Dir before passing them to Handler.
+// Encrypted messages are decrypted with production keys,
+// so that fixtures could be replayed with test keys.
+type Recorder struct {
+	Dir     string
+	Kind    string
+	Handler http.Handler
+
+	// for KindMessage, AppID is component appid for component notify.
+	AppID          string
+	Token          string
+	EncodingAESKey string
+	// for KindPay, to decrypt req_info of refund notice.
+	PaymentKey string
+}
+
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	err = r.record(req, body)
+	if err != nil {
+		log.Print("record callback error: ", err)
+	}
+
+	r.Handler.ServeHTTP(w, req)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) error {
+	plain, err := r.plainBody(req, body)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	f := &Fixture{
+		Kind:     r.Kind,
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Query:    req.URL.Query(),
+		Header:   req.Header,
+		Body:     string(plain),
+		RecordAt: now.UnixNano(),
+	}
+	// signatures are recomputed on replay
+	for _, k := range []string{"signature", "msg_signature", "timestamp", "nonce"} {
+		f.Query.Del(k)
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(r.Dir, 0755)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(r.Dir, fmt.Sprintf("%s-%d.json", r.Kind, now.UnixNano()))
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+func (r *Recorder) plainBody(req *http.Request, body []byte) ([]byte, error) {
+	if len(body) == 0 {
+		return body, nil
+	}
+
+	switch r.Kind {
+	case KindMessage:
+		q := req.URL.Query()
+		if q.Get("encrypt_type") != message.EncryptTypeAES {
+			return body, nil
+		}
+		encoding, err := crypto.New(r.EncodingAESKey, r.Token, r.AppID)
+		if err != nil {
+			return nil, err
+		}
+		return encoding.Decrypt(body, q.Get("msg_signature"), q.Get("nonce"), q.Get("timestamp"))
+
+	case KindPay:
+		return pay.PlainNotice(body, r.PaymentKey)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,137 @@
+package replay
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MenInBack/weshin/crypto"
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/pay"
+)
+
+const (
+	appID       = "wx2c2769f8efd9abc2"
+	prodToken   = "spamtest"
+	prodKey     = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	testToken   = "testtoken"
+	testKey     = "0123456789ABCDEFGabcdefghijklmnopqrstuvwxyz"
+	textMessage = `<xml><ToUserName><![CDATA[gh_10f6c3c3ac5a]]></ToUserName><FromUserName><![CDATA[oyORnuP8q7ou2gfYjqLzSIWZf0rs]]></FromUserName><CreateTime>1409735668</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[hello]]></Content><MsgId>6054768590064713728</MsgId></xml>`
+)
+
+func echoServer(token, key string, got *string) *message.Server {
+	return &message.Server{
+		AppID:          appID,
+		Token:          token,
+		EncodingAESKey: key,
+		Handler: func(msg *message.Message) *message.Message {
+			*got = msg.Content.(*message.Text).Content
+			return nil
+		},
+	}
+}
+
+func TestReplayMessage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// production push
+	var got string
+	rec := &Recorder{
+		Dir:            dir,
+		Kind:           KindMessage,
+		Handler:        echoServer(prodToken, prodKey, &got),
+		AppID:          appID,
+		Token:          prodToken,
+		EncodingAESKey: prodKey,
+	}
+	encoding, _ := crypto.New(prodKey, prodToken, appID)
+	body, err := encoding.Encrypt([]byte(textMessage), "nonce", "1409735669")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encrypted struct {
+		MsgSignature string `xml:"MsgSignature"`
+	}
+	xml.Unmarshal(body, &encrypted)
+	sig := string(crypto.Signature([]string{"1409735669", "nonce", prodToken}))
+	req := httptest.NewRequest("POST", "/callback?signature="+sig+"&timestamp=1409735669&nonce=nonce&encrypt_type=aes&msg_signature="+encrypted.MsgSignature, strings.NewReader(string(body)))
+	rec.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "hello" {
+		t.Fatal("recorded message not handled")
+	}
+
+	fixtures, err := LoadFixtures(dir)
+	if err != nil || len(fixtures) != 1 {
+		t.Fatal("load fixtures failed: ", err)
+	}
+	if fixtures[0].Body != textMessage {
+		t.Error("message not recorded in plain text: ", fixtures[0].Body)
+	}
+
+	got = ""
+	r := &Replayer{AppID: appID, Token: testToken, EncodingAESKey: testKey}
+	resp, err := r.Replay(fixtures[0], echoServer(testToken, testKey, &got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || got != "hello" {
+		t.Error("replay message failed: ", resp.Status)
+	}
+}
+
+type payNoticeHandler struct {
+	notice *pay.PayNotice
+}
+
+func (h *payNoticeHandler) HandlePayNotice(n *pay.PayNotice) error {
+	h.notice = n
+	return nil
+}
+
+func TestReplayPayNotice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notice := `<xml><appid><![CDATA[wx2421b1c4370ec43b]]></appid><mch_id><![CDATA[10000100]]></mch_id><out_trade_no><![CDATA[1409811653]]></out_trade_no><result_code><![CDATA[SUCCESS]]></result_code><return_code><![CDATA[SUCCESS]]></return_code><total_fee>1</total_fee></xml>`
+	body, err := pay.ResignNotice([]byte(notice), "productionkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &Recorder{
+		Dir:        dir,
+		Kind:       KindPay,
+		Handler:    http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}),
+		PaymentKey: "productionkey",
+	}
+	rec.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/pay/notice", strings.NewReader(string(body))))
+
+	fixtures, err := LoadFixtures(dir)
+	if err != nil || len(fixtures) != 1 {
+		t.Fatal("load fixtures failed: ", err)
+	}
+
+	h := new(payNoticeHandler)
+	m := &pay.MerchantInfo{
+		AppID:           "wx2421b1c4370ec43b",
+		MerchantID:      "10000100",
+		PaymentKey:      "testkey",
+		PayNoticeHander: h,
+	}
+	r := &Replayer{PaymentKey: "testkey"}
+	_, err = r.Replay(fixtures[0], http.HandlerFunc(m.PayNotice))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.notice == nil || h.notice.TradeNo.Data != "1409811653" {
+		t.Errorf("replay pay notice failed: %+v", h.notice)
+	}
+}
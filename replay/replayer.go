@@ -0,0 +1,96 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/MenInBack/weshin/crypto"
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/pay"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// Replayer replays fixtures signed and encrypted with test keys.
+type Replayer struct {
+	// for KindMessage
+	AppID          string
+	Token          string
+	EncodingAESKey string
+	// for KindPay
+	PaymentKey string
+}
+
+// Replay fixture into handler, and returns the response.
+func (r *Replayer) Replay(f *Fixture, h http.Handler) (*http.Response, error) {
+	req, err := r.Request(f)
+	if err != nil {
+		return nil, err
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Result(), nil
+}
+
+// Request composes request from fixture, with signatures recomputed.
+func (r *Replayer) Request(f *Fixture) (*http.Request, error) {
+	body := []byte(f.Body)
+	query := make(url.Values, len(f.Query))
+	for k, v := range f.Query {
+		query[k] = v
+	}
+
+	switch f.Kind {
+	case KindMessage:
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		nonce := string(crypto.RandString(10))
+		query.Set("timestamp", timestamp)
+		query.Set("nonce", nonce)
+		query.Set("signature", string(crypto.Signature([]string{timestamp, nonce, r.Token})))
+
+		if len(body) > 0 && f.Query.Get("encrypt_type") == message.EncryptTypeAES {
+			encoding, err := crypto.New(r.EncodingAESKey, r.Token, r.AppID)
+			if err != nil {
+				return nil, err
+			}
+			body, err = encoding.Encrypt(body, nonce, timestamp)
+			if err != nil {
+				return nil, err
+			}
+
+			var encrypted struct {
+				MsgSignature string `xml:"MsgSignature"`
+			}
+			err = xml.Unmarshal(body, &encrypted)
+			if err != nil {
+				return nil, err
+			}
+			query.Set("msg_signature", encrypted.MsgSignature)
+		}
+
+	case KindPay:
+		var err error
+		body, err = pay.ResignNotice(body, r.PaymentKey)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, wx.ParameterError{InvalidParameter: "fixture kind " + f.Kind}
+	}
+
+	req := httptest.NewRequest(f.Method, f.Path, bytes.NewReader(body))
+	req.URL.RawQuery = query.Encode()
+	for k, v := range f.Header {
+		if k == "Content-Length" {
+			continue
+		}
+		req.Header[k] = v
+	}
+	return req, nil
+}
@@ -0,0 +1,67 @@
+package usersync
+
+import (
+	"time"
+
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// Handle feeds subscribe and unsubscribe events into Store in real time,
+// messages are passed to next handler if not nil.
+func (j *Job) Handle(next message.MessageHandler) message.MessageHandler {
+	return func(msg *message.Message) *message.Message {
+		if e, ok := msg.Content.(*message.Event); ok {
+			switch e.Event {
+			case message.EventSubscribe:
+				go j.subscribe(msg.FromUserName)
+			case message.EventUnsubscribe:
+				go j.unsubscribe(msg.FromUserName, msg.CreateTime)
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		return next(msg)
+	}
+}
+
+func (j *Job) subscribe(openID string) {
+	info, err := j.Source.GetUserInfo(openID, j.Lang, j.Timeout)
+	if err != nil {
+		j.notifyError(err)
+		return
+	}
+	err = j.Store.SaveUser(info, Digest(info), time.Now().Unix())
+	if err != nil {
+		j.notifyError(err)
+		return
+	}
+
+	// not to be swept by the run in process
+	cp, err := j.Store.GetCheckpoint()
+	if err == nil && cp != nil {
+		err = j.Store.MarkSeen(cp.RunID, []string{openID})
+	}
+	if err != nil {
+		j.notifyError(err)
+	}
+}
+
+func (j *Job) unsubscribe(openID string, at int64) {
+	err := j.Store.MarkUnsubscribed(openID, at)
+	if err != nil {
+		j.notifyError(err)
+	}
+}
+
+func (j *Job) notifyError(err error) {
+	if j.Errors == nil {
+		return
+	}
+	select {
+	case j.Errors <- wx.NotifyError{Err: err}:
+	default:
+	}
+}
@@ -0,0 +1,31 @@
+package usersync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MenInBack/weshin/message"
+	"github.com/MenInBack/weshin/wx"
+)
+
+func TestHandleErrors(t *testing.T) {
+	job := &Job{
+		Source: &fakeSource{failAt: "openid01"},
+		Store:  newMemoryStore(),
+		Errors: make(chan error, 1),
+	}
+	handle := job.Handle(nil)
+	handle(&message.Message{
+		Meta:    message.Meta{FromUserName: "openid01"},
+		Content: &message.Event{Event: message.EventSubscribe},
+	})
+
+	select {
+	case err := <-job.Errors:
+		if _, ok := err.(wx.NotifyError); !ok {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expect error of subscriber")
+	}
+}
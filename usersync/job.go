@@ -0,0 +1,163 @@
+package usersync
+
+// sync followers of official account into a local store incrementally:
+// followers are listed page by page, only new or stale users are fetched,
+// and users no longer following are marked unsubscribed in the end.
+// Checkpoint is saved after each page, so that an interrupted run resumes.
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/MenInBack/weshin/base"
+	"github.com/MenInBack/weshin/wx"
+)
+
+// Source of followers and user info, implemented by *base.MP.
+type Source interface {
+	GetFollowers(nextOpenID string, timeout int) (*base.OpenIDList, error)
+	FetchUserInfo(it *base.OpenIDIterator, lang string, concurrency, timeout int, handle base.UserInfoHandler) error
+	GetUserInfo(openID, lang string, timeout int) (*wx.UserInfo, error)
+}
+
+// Job syncs followers from Source into Store.
+type Job struct {
+	Source Source
+	Store  Store
+
+	Lang        string
+	Concurrency int // for fetching user info
+	Timeout     int
+	// RefreshAfter refetches users synced earlier than it,
+	// saved users are not refetched if zero.
+	RefreshAfter time.Duration
+
+	// Errors receives errors when handling subscribe events if set,
+	// errors are dropped if nobody is receiving.
+	Errors chan error
+}
+
+// Run the job to the end, resumed from checkpoint if any.
+// Checkpoint is kept for the next run if error returned.
+func (j *Job) Run() (*Stats, error) {
+	cp, err := j.Store.GetCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil {
+		cp = &Checkpoint{RunID: strconv.FormatInt(time.Now().UnixNano(), 10)}
+	}
+
+	for {
+		list, err := j.Source.GetFollowers(cp.NextOpenID, j.Timeout)
+		if err != nil {
+			return &cp.Stats, err
+		}
+		if list.Count == 0 || len(list.Data.OpenID) == 0 {
+			break
+		}
+
+		err = j.syncPage(cp, list.Data.OpenID)
+		if err != nil {
+			// keep stats of users saved, the page is synced again on resume
+			if e := j.Store.SaveCheckpoint(cp); e != nil {
+				return &cp.Stats, e
+			}
+			return &cp.Stats, err
+		}
+
+		last := list.NextOpenID == "" || list.NextOpenID == cp.NextOpenID
+		cp.NextOpenID = list.NextOpenID
+		err = j.Store.SaveCheckpoint(cp)
+		if err != nil {
+			return &cp.Stats, err
+		}
+		if last {
+			break
+		}
+	}
+
+	n, err := j.Store.SweepUnsubscribed(cp.RunID, time.Now().Unix())
+	if err != nil {
+		return &cp.Stats, err
+	}
+	cp.Unsubscribed += n
+
+	return &cp.Stats, j.Store.SaveCheckpoint(nil)
+}
+
+func (j *Job) syncPage(cp *Checkpoint, openIDs []string) error {
+	states, err := j.Store.LookupUsers(openIDs)
+	if err != nil {
+		return err
+	}
+	err = j.Store.MarkSeen(cp.RunID, openIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stale := now.Add(-j.RefreshAfter).Unix()
+	var fetch []string
+	for _, id := range openIDs {
+		s, ok := states[id]
+		if !ok || !s.Subscribed || (j.RefreshAfter > 0 && s.SyncAt < stale) {
+			fetch = append(fetch, id)
+		}
+	}
+
+	var errs []error
+	err = j.Source.FetchUserInfo(base.NewOpenIDSliceIterator(fetch), j.Lang, j.Concurrency, j.Timeout, func(infos []*wx.UserInfo, openIDs []string, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		for _, info := range infos {
+			err = j.saveUser(cp, info, states[info.OpenID], now.Unix())
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return PageError{errs}
+	}
+
+	cp.Followers += len(openIDs)
+	return nil
+}
+
+func (j *Job) saveUser(cp *Checkpoint, info *wx.UserInfo, state *UserState, syncAt int64) error {
+	d := Digest(info)
+	switch {
+	case state == nil || !state.Subscribed:
+		cp.New++
+	case state.Digest != d:
+		cp.Changed++
+	}
+	return j.Store.SaveUser(info, d, syncAt)
+}
+
+// PageError holds errors of chunks failed in a page,
+// the page is synced again on resume.
+type PageError struct {
+	Errs []error
+}
+
+func (e PageError) Error() string {
+	return "usersync: " + strconv.Itoa(len(e.Errs)) + " chunks failed, first: " + e.Errs[0].Error()
+}
+
+// Digest of user info, to tell whether user info changed.
+func Digest(info *wx.UserInfo) string {
+	data, _ := json.Marshal(info)
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package usersync
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/MenInBack/weshin/base"
+	"github.com/MenInBack/weshin/wx"
+)
+
+type fakeSource struct {
+	followers []string
+	pageSize  int
+	fetched   int
+	failAt    string // openid failed to fetch
+}
+
+func (s *fakeSource) GetFollowers(nextOpenID string, timeout int) (*base.OpenIDList, error) {
+	start := 0
+	for i, id := range s.followers {
+		if id == nextOpenID {
+			start = i + 1
+		}
+	}
+	list := new(base.OpenIDList)
+	for i := start; i < len(s.followers) && i < start+s.pageSize; i++ {
+		list.Data.OpenID = append(list.Data.OpenID, s.followers[i])
+	}
+	list.Count = int64(len(list.Data.OpenID))
+	if list.Count > 0 {
+		list.NextOpenID = list.Data.OpenID[list.Count-1]
+	}
+	return list, nil
+}
+
+func (s *fakeSource) FetchUserInfo(it *base.OpenIDIterator, lang string, concurrency, timeout int, handle base.UserInfoHandler) error {
+	for it.Next() {
+		id := it.OpenID()
+		if id == s.failAt {
+			handle(nil, []string{id}, errors.New("fetch failed"))
+			continue
+		}
+		info, _ := s.GetUserInfo(id, lang, timeout)
+		handle([]*wx.UserInfo{info}, []string{id}, nil)
+	}
+	return it.Err()
+}
+
+func (s *fakeSource) GetUserInfo(openID, lang string, timeout int) (*wx.UserInfo, error) {
+	if openID == s.failAt {
+		return nil, errors.New("get failed")
+	}
+	s.fetched++
+	return &wx.UserInfo{Subscribe: 1, OpenID: openID, Nickname: "nick " + openID}, nil
+}
+
+type memoryStore struct {
+	users      map[string]*UserState
+	seen       map[string]map[string]bool
+	checkpoint *Checkpoint
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users: make(map[string]*UserState),
+		seen:  make(map[string]map[string]bool),
+	}
+}
+
+func (s *memoryStore) LookupUsers(openIDs []string) (map[string]*UserState, error) {
+	states := make(map[string]*UserState)
+	for _, id := range openIDs {
+		if u, ok := s.users[id]; ok {
+			states[id] = u
+		}
+	}
+	return states, nil
+}
+
+func (s *memoryStore) SaveUser(info *wx.UserInfo, digest string, syncAt int64) error {
+	s.users[info.OpenID] = &UserState{Subscribed: info.Subscribe == 1, Digest: digest, SyncAt: syncAt}
+	return nil
+}
+
+func (s *memoryStore) MarkUnsubscribed(openID string, at int64) error {
+	if u, ok := s.users[openID]; ok {
+		u.Subscribed = false
+	}
+	return nil
+}
+
+func (s *memoryStore) MarkSeen(runID string, openIDs []string) error {
+	if s.seen[runID] == nil {
+		s.seen[runID] = make(map[string]bool)
+	}
+	for _, id := range openIDs {
+		s.seen[runID][id] = true
+	}
+	return nil
+}
+
+func (s *memoryStore) SweepUnsubscribed(runID string, at int64) (int, error) {
+	n := 0
+	for id, u := range s.users {
+		if u.Subscribed && !s.seen[runID][id] {
+			u.Subscribed = false
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memoryStore) GetCheckpoint() (*Checkpoint, error) {
+	return s.checkpoint, nil
+}
+
+func (s *memoryStore) SaveCheckpoint(cp *Checkpoint) error {
+	s.checkpoint = cp
+	return nil
+}
+
+func openIDs(from, to int) []string {
+	var ids []string
+	for i := from; i < to; i++ {
+		ids = append(ids, fmt.Sprintf("openid%02d", i))
+	}
+	return ids
+}
+
+func TestJobResume(t *testing.T) {
+	source := &fakeSource{followers: openIDs(0, 25), pageSize: 10, failAt: "openid15"}
+	store := newMemoryStore()
+	job := &Job{Source: source, Store: store}
+
+	stats, err := job.Run()
+	if err == nil {
+		t.Fatal("expect error at page 2")
+	}
+	if store.checkpoint == nil || store.checkpoint.NextOpenID != "openid09" || stats.New != 19 {
+		t.Fatalf("expect checkpoint after page 1, got %+v", store.checkpoint)
+	}
+
+	source.failAt = ""
+	source.fetched = 0
+	stats, err = job.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.fetched != 6 {
+		t.Errorf("expect 6 users fetched on resume, got %d", source.fetched)
+	}
+	if stats.Followers != 25 || stats.New != 25 || store.checkpoint != nil {
+		t.Errorf("unexpected stats %+v, checkpoint %+v", stats, store.checkpoint)
+	}
+
+	// incremental run
+	source.followers = append(openIDs(5, 25), openIDs(30, 32)...)
+	source.fetched = 0
+	stats, err = job.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.fetched != 2 || stats.New != 2 || stats.Unsubscribed != 5 {
+		t.Errorf("expect 2 new and 5 unsubscribed, got %+v, fetched %d", stats, source.fetched)
+	}
+}
@@ -0,0 +1,48 @@
+package usersync
+
+import (
+	"github.com/MenInBack/weshin/wx"
+)
+
+// Store holds local copy of followers, and checkpoint of sync job.
+type Store interface {
+	// LookupUsers returns states of openids saved, openids not saved are omitted.
+	LookupUsers(openIDs []string) (map[string]*UserState, error)
+	// SaveUser saves user info fetched at syncAt, with digest of it.
+	SaveUser(info *wx.UserInfo, digest string, syncAt int64) error
+	// MarkUnsubscribed marks user unsubscribed at time.
+	MarkUnsubscribed(openID string, at int64) error
+
+	// MarkSeen marks openids as followers found in run.
+	MarkSeen(runID string, openIDs []string) error
+	// SweepUnsubscribed marks subscribed users not seen in run as unsubscribed at time,
+	// returns number of users marked.
+	SweepUnsubscribed(runID string, at int64) (int, error)
+
+	// GetCheckpoint returns checkpoint of the unfinished run, nil if none.
+	GetCheckpoint() (*Checkpoint, error)
+	// SaveCheckpoint after each page synced, nil to clear when run finished.
+	SaveCheckpoint(*Checkpoint) error
+}
+
+// UserState of user saved.
+type UserState struct {
+	Subscribed bool
+	Digest     string // of user info saved
+	SyncAt     int64  // unix time of user info fetched
+}
+
+// Checkpoint of a run, to resume from.
+type Checkpoint struct {
+	RunID      string `json:"runID"`
+	NextOpenID string `json:"nextOpenID"`
+	Stats
+}
+
+// Stats of a run.
+type Stats struct {
+	Followers    int `json:"followers"`    // followers seen
+	New          int `json:"new"`          // new followers saved
+	Changed      int `json:"changed"`      // followers with user info changed
+	Unsubscribed int `json:"unsubscribed"` // followers gone since last run
+}
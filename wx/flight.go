@@ -0,0 +1,45 @@
+package wx
+
+import (
+	"sync"
+)
+
+// Flight runs one call at a time for each key,
+// callers of the key arriving while a call is in process wait for and share its result.
+type Flight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Do calls fn for key, or waits for the call of key in process.
+func (f *Flight) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	f.mu.Lock()
+	if c, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		<-c.done
+		return c.value, c.err
+	}
+	c := &flightCall{done: make(chan struct{})}
+	if f.calls == nil {
+		f.calls = make(map[string]*flightCall)
+	}
+	f.calls[key] = c
+	f.mu.Unlock()
+
+	// release waiting callers even if fn panics
+	defer func() {
+		f.mu.Lock()
+		delete(f.calls, key)
+		f.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.value, c.err = fn()
+	return c.value, c.err
+}
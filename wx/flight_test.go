@@ -0,0 +1,66 @@
+package wx
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlight(t *testing.T) {
+	var f Flight
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := f.Do("key", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(20 * time.Millisecond)
+				return "value", nil
+			})
+			if v != "value" || err != nil {
+				t.Errorf("got %v, error %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("called %d times, expect 1", calls)
+	}
+
+	// called again after finished
+	f.Do("key", func() (interface{}, error) {
+		calls++
+		return nil, nil
+	})
+	if calls != 2 {
+		t.Errorf("called %d times, expect 2", calls)
+	}
+}
+
+func TestFlightPanic(t *testing.T) {
+	var f Flight
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expect panic of fn")
+			}
+		}()
+		f.Do("key", func() (interface{}, error) {
+			panic("fn failed")
+		})
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		f.Do("key", func() (interface{}, error) { return nil, nil })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("key not released after panic")
+	}
+}
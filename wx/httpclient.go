@@ -6,6 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -53,7 +54,7 @@ func (c *HttpClient) DoPost(body io.Reader, value interface{}) (err error) {
 		return err
 	}
 	c.req = req
-	req.Header.Set("content_type", c.ContentType)
+	req.Header.Set("Content-Type", c.ContentType)
 
 	err = c.prepareQueries()
 	if err != nil {
@@ -83,7 +84,7 @@ func (c *HttpClient) prepareQueries() error {
 	return nil
 }
 
-func (c *HttpClient) request(value interface{}) error {
+func (c *HttpClient) do() (*http.Response, error) {
 	client := http.Client{
 		Timeout: func() time.Duration {
 			if c.Timeout > 0 {
@@ -95,18 +96,30 @@ func (c *HttpClient) request(value interface{}) error {
 
 	resp, err := client.Do(c.req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		err = HttpError{
+		resp.Body.Close()
+		return nil, HttpError{
 			State: resp.StatusCode,
 		}
+	}
+	return resp, nil
+}
+
+func (c *HttpClient) request(value interface{}) error {
+	resp, err := c.do()
+	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	return unmarshalResponse(resp.Body, value)
+}
 
-	data, err := ioutil.ReadAll(resp.Body)
+func unmarshalResponse(body io.Reader, value interface{}) error {
+	data, err := ioutil.ReadAll(body)
 	if err != nil {
 		return err
 	}
@@ -115,6 +128,9 @@ func (c *HttpClient) request(value interface{}) error {
 	if err != nil {
 		return err
 	}
+	if value == nil {
+		return nil
+	}
 
 	err = json.Unmarshal(data, value)
 	if err != nil {
@@ -123,3 +139,39 @@ func (c *HttpClient) request(value interface{}) error {
 
 	return nil
 }
+
+// Download requests with method and body, binary response is copied into w,
+// ParameterError if w is nil, while json response is unmarshaled into value
+// if not nil, or returned as error.
+func (c *HttpClient) Download(method string, body io.Reader, w io.Writer, value interface{}) (binary bool, err error) {
+	req, err := http.NewRequest(method, c.Path, body)
+	if err != nil {
+		return false, err
+	}
+	c.req = req
+	if body != nil {
+		req.Header.Set("Content-Type", c.ContentType)
+	}
+
+	err = c.prepareQueries()
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.do()
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/plain") {
+		return false, unmarshalResponse(resp.Body, value)
+	}
+
+	if w == nil {
+		return true, ParameterError{InvalidParameter: "w: nil for binary response of " + contentType}
+	}
+	_, err = io.Copy(w, resp.Body)
+	return true, err
+}
@@ -0,0 +1,69 @@
+package wx
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostContentType(t *testing.T) {
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		contentType = req.Header.Get("Content-Type")
+		w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	for _, typ := range []string{"application/json", "multipart/form-data; boundary=xxx"} {
+		req := HttpClient{Path: server.URL, ContentType: typ}
+		err := req.DoPost(strings.NewReader("{}"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != typ {
+			t.Errorf("posted with Content-Type %q, expect %q", contentType, typ)
+		}
+
+		_, err = req.Download("POST", strings.NewReader("{}"), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != typ {
+			t.Errorf("downloaded with Content-Type %q, expect %q", contentType, typ)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("type") == "json" {
+			w.Header().Set("Content-Type", "application/json; encoding=utf-8")
+			w.Write([]byte(`{"errcode":40007,"errmsg":"invalid media_id"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("binary"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	req := HttpClient{Path: server.URL}
+	binary, err := req.Download("GET", nil, &buf, nil)
+	if err != nil || !binary || buf.String() != "binary" {
+		t.Errorf("downloaded %q, binary %v, error %v", buf.String(), binary, err)
+	}
+
+	// binary response without writer
+	binary, err = req.Download("GET", nil, nil, nil)
+	if _, ok := err.(ParameterError); !ok || !binary {
+		t.Errorf("binary %v, error %v", binary, err)
+	}
+
+	req.Parameters = []QueryParameter{{Key: "type", Value: "json"}}
+	binary, err = req.Download("GET", nil, nil, nil)
+	if _, ok := err.(*WechatError); !ok || binary {
+		t.Errorf("binary %v, error %v", binary, err)
+	}
+}